@@ -0,0 +1,203 @@
+// Package cache provides an in-process TTL cache for upstream API responses,
+// optionally backed by a persistent Store.
+//
+// Values are stored JSON encoded, so callers never share (and accidentally
+// mutate) the slices and maps held by the cache.
+package cache
+
+import (
+	"container/list"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Cache is a size-bounded TTL cache. When the cache is full, the least recently used entry is evicted.
+//
+// Expired entries are kept until they are evicted, so they can be served while being
+// revalidated in the background, and as a fallback when the upstream API is down (see Fetch).
+type Cache struct {
+	mu                   sync.Mutex
+	ttl                  time.Duration
+	staleWhileRevalidate time.Duration
+	maxSize              int
+	entries              map[string]*list.Element
+	lru                  *list.List      // Front is the most recently used entry
+	store                Store           // Optional persistent backing store
+	refreshing           map[string]bool // Keys with a background refresh in progress
+
+	hits   atomic.Int64
+	misses atomic.Int64
+	stale  atomic.Int64
+}
+
+// Stats is a snapshot of the cache counters.
+type Stats struct {
+	Hits                 int64
+	Misses               int64
+	Stale                int64
+	Entries              int
+	MaxSize              int
+	TTL                  time.Duration
+	StaleWhileRevalidate time.Duration
+	Persistent           bool
+}
+
+// New creates a cache holding at most maxSize entries for ttl each.
+//
+// Parameters:
+//   - ttl: How long an entry is considered fresh.
+//   - maxSize: The maximum number of entries. Values below 1 are treated as 1.
+//
+// Returns:
+//   - *Cache: An empty cache.
+func New(ttl time.Duration, maxSize int) *Cache {
+	if maxSize < 1 {
+		maxSize = 1
+	}
+	return &Cache{
+		ttl:        ttl,
+		maxSize:    maxSize,
+		entries:    make(map[string]*list.Element),
+		lru:        list.New(),
+		refreshing: make(map[string]bool),
+	}
+}
+
+// SetStore attaches a persistent store. Entries are written through to the store,
+// and looked up in it when they are not held in memory.
+func (c *Cache) SetStore(store Store) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.store = store
+}
+
+// SetStaleWhileRevalidate sets how long after expiring an entry is still served
+// immediately, while it is refreshed in the background.
+func (c *Cache) SetStaleWhileRevalidate(window time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.staleWhileRevalidate = window
+}
+
+// TTL returns how long entries are considered fresh.
+func (c *Cache) TTL() time.Duration {
+	return c.ttl
+}
+
+// StaleWhileRevalidate returns how long after expiring an entry is still served while being refreshed.
+func (c *Cache) StaleWhileRevalidate() time.Duration {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.staleWhileRevalidate
+}
+
+// Lookup returns the value stored under key and the time it was stored, regardless of its age.
+func (c *Cache) Lookup(key string) ([]byte, time.Time, bool) {
+	entry, ok := c.lookup(key)
+	if !ok {
+		return nil, time.Time{}, false
+	}
+	return entry.Value, entry.StoredAt, true
+}
+
+// Set stores value under key, evicting the least recently used entry if the cache is full.
+// The entry is written through to the persistent store, if any.
+func (c *Cache) Set(key string, value []byte) {
+	entry := &Entry{Key: key, StoredAt: time.Now(), Value: value}
+
+	c.mu.Lock()
+	c.insert(entry)
+	store := c.store
+	c.mu.Unlock()
+
+	if store != nil {
+		if err := store.Save(*entry); err != nil {
+			log.Printf("Error persisting cache entry %s: %v", key, err)
+		}
+	}
+}
+
+// Stats returns a snapshot of the cache counters.
+func (c *Cache) Stats() Stats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return Stats{
+		Hits:                 c.hits.Load(),
+		Misses:               c.misses.Load(),
+		Stale:                c.stale.Load(),
+		Entries:              c.lru.Len(),
+		MaxSize:              c.maxSize,
+		TTL:                  c.ttl,
+		StaleWhileRevalidate: c.staleWhileRevalidate,
+		Persistent:           c.store != nil,
+	}
+}
+
+// startRefresh marks key as being refreshed. It returns false if a refresh is already in progress.
+func (c *Cache) startRefresh(key string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.refreshing[key] {
+		return false
+	}
+	c.refreshing[key] = true
+	return true
+}
+
+// endRefresh marks the refresh of key as done.
+func (c *Cache) endRefresh(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.refreshing, key)
+}
+
+// lookup returns the entry stored under key, loading it from the persistent store if it is not held in memory.
+func (c *Cache) lookup(key string) (*Entry, bool) {
+	c.mu.Lock()
+	if element, ok := c.entries[key]; ok {
+		c.lru.MoveToFront(element)
+		c.mu.Unlock()
+		return element.Value.(*Entry), true
+	}
+	store := c.store
+	c.mu.Unlock()
+
+	// Read from the store without holding the lock, as it may involve disk I/O
+	if store == nil {
+		return nil, false
+	}
+	stored, ok := store.Load(key)
+	if !ok {
+		return nil, false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if element, ok := c.entries[key]; ok { // Stored concurrently, keep the newer in-memory entry
+		return element.Value.(*Entry), true
+	}
+	entry := &stored
+	c.insert(entry)
+	return entry, true
+}
+
+// insert adds or replaces the entry in memory, evicting the least recently used entries
+// if the cache is full. The caller must hold c.mu.
+func (c *Cache) insert(entry *Entry) {
+	if element, ok := c.entries[entry.Key]; ok {
+		element.Value = entry
+		c.lru.MoveToFront(element)
+		return
+	}
+
+	c.entries[entry.Key] = c.lru.PushFront(entry)
+	for c.lru.Len() > c.maxSize {
+		oldest := c.lru.Back()
+		c.lru.Remove(oldest)
+		delete(c.entries, oldest.Value.(*Entry).Key)
+	}
+}
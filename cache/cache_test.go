@@ -0,0 +1,101 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// countingFetch returns a fetch function returning value, counting its calls in calls.
+func countingFetch[T any](value T, err error, calls *int) func(context.Context) (T, error) {
+	return func(context.Context) (T, error) {
+		*calls++
+		return value, err
+	}
+}
+
+func TestFetchCachesValues(t *testing.T) {
+	c := New(time.Hour, 10)
+	calls := 0
+
+	for i := 0; i < 3; i++ {
+		value, err := Fetch(context.Background(), c, "key", countingFetch([]string{"Oslo"}, nil, &calls))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(value) != 1 || value[0] != "Oslo" {
+			t.Fatalf("unexpected value %v", value)
+		}
+	}
+
+	if calls != 1 {
+		t.Errorf("expected the upstream API to be called once, got %d calls", calls)
+	}
+	if stats := c.Stats(); stats.Hits != 2 || stats.Misses != 1 || stats.Entries != 1 {
+		t.Errorf("unexpected counters %+v", stats)
+	}
+}
+
+func TestFetchDoesNotCacheErrors(t *testing.T) {
+	c := New(time.Hour, 10)
+	calls := 0
+	upstreamErr := errors.New("upstream unavailable")
+
+	for i := 0; i < 2; i++ {
+		if _, err := Fetch(context.Background(), c, "key", countingFetch(0, upstreamErr, &calls)); !errors.Is(err, upstreamErr) {
+			t.Fatalf("expected the upstream error, got %v", err)
+		}
+	}
+	if calls != 2 {
+		t.Errorf("expected every call to reach the upstream API, got %d calls", calls)
+	}
+}
+
+func TestFetchWithoutCache(t *testing.T) {
+	calls := 0
+	for i := 0; i < 2; i++ {
+		if _, err := Fetch(context.Background(), nil, "key", countingFetch(1, nil, &calls)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if calls != 2 {
+		t.Errorf("expected every call to reach the upstream API without a cache, got %d calls", calls)
+	}
+}
+
+func TestFetchRecordsMeta(t *testing.T) {
+	c := New(time.Hour, 10)
+	c.SetStaleWhileRevalidate(time.Minute)
+	ctx, meta := WithMeta(context.Background())
+
+	if _, err := Fetch(ctx, c, "key", countingFetch(1, nil, new(int))); err != nil {
+		t.Fatal(err)
+	}
+	if maxAge, ok := meta.MaxAge(); !ok || maxAge != time.Hour {
+		t.Errorf("expected a max age of one hour, got %s (%t)", maxAge, ok)
+	}
+	if window := meta.StaleWhileRevalidate(); window != time.Minute {
+		t.Errorf("expected a stale-while-revalidate window of one minute, got %s", window)
+	}
+	if meta.Stale() {
+		t.Error("expected a freshly fetched value not to be stale")
+	}
+}
+
+func TestCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	c := New(time.Hour, 2)
+	c.Set("a", []byte("1"))
+	c.Set("b", []byte("2"))
+	c.Lookup("a") // Makes b the least recently used entry
+	c.Set("c", []byte("3"))
+
+	if _, _, ok := c.Lookup("b"); ok {
+		t.Error("expected the least recently used entry to be evicted")
+	}
+	for _, key := range []string{"a", "c"} {
+		if _, _, ok := c.Lookup(key); !ok {
+			t.Errorf("expected entry %s to be kept", key)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"time"
+)
+
+// backgroundRefreshTimeout bounds a background refresh, which outlives the request that triggered it.
+const backgroundRefreshTimeout = 30 * time.Second
+
+// Fetch returns the value cached under key, or calls fetch on a miss and caches its result.
+//
+// Depending on the age of the cached value:
+//   - Fresh (younger than the TTL): the value is served as is.
+//   - Stale, but within the stale-while-revalidate window: the value is served immediately
+//     and refreshed in the background.
+//   - Older: fetch is called. If it fails, the last known good value is served instead, however old.
+//
+// The freshness of every value used is recorded in the Meta attached to ctx (see WithMeta), so
+// handlers can derive the Cache-Control, Age and staleness headers of their response.
+//
+// Parameters:
+//   - ctx: The request context, passed on to fetch.
+//   - c: The cache to use. If nil, fetch is always called.
+//   - key: The cache key, identifying the upstream endpoint and ISO code.
+//   - fetch: The function retrieving the value from the upstream API.
+//
+// Returns:
+//   - T: The cached or freshly fetched value.
+//   - error: The error returned by fetch if no fallback value exists. Errors are never cached.
+func Fetch[T any](ctx context.Context, c *Cache, key string, fetch func(context.Context) (T, error)) (T, error) {
+	meta := metaFrom(ctx)
+	if c == nil {
+		return fetch(ctx)
+	}
+
+	window := c.StaleWhileRevalidate()
+	var cached T
+	data, storedAt, found := c.Lookup(key)
+	if found && json.Unmarshal(data, &cached) != nil {
+		log.Printf("Discarding undecodable cache entry %s", key)
+		found = false
+	}
+
+	if found {
+		age := time.Since(storedAt)
+		if age <= c.ttl {
+			c.hits.Add(1)
+			meta.record(storedAt, c.ttl, window, false, false)
+			return cached, nil
+		}
+		if age <= c.ttl+window {
+			c.stale.Add(1)
+			meta.record(storedAt, c.ttl, window, true, false)
+			refreshInBackground(ctx, c, key, fetch)
+			return cached, nil
+		}
+	}
+
+	c.misses.Add(1)
+	value, err := fetch(ctx)
+	if err != nil {
+		if !found {
+			return value, err
+		}
+		// Serve the last known good value, however old, while the upstream API is down
+		log.Printf("Serving stale cache entry %s stored at %s: %v", key, storedAt.Format(time.RFC3339), err)
+		c.stale.Add(1)
+		meta.record(storedAt, c.ttl, window, true, true)
+		return cached, nil
+	}
+
+	store(c, key, value)
+	meta.record(time.Now(), c.ttl, window, false, false)
+	return value, nil
+}
+
+// refreshInBackground fetches a fresh value for key without blocking the caller.
+// At most one refresh per key runs at a time; failed refreshes keep the stale value in place.
+func refreshInBackground[T any](ctx context.Context, c *Cache, key string, fetch func(context.Context) (T, error)) {
+	if !c.startRefresh(key) {
+		return
+	}
+
+	// Detach from the request, which will be answered before the refresh is done,
+	// and from its Meta, which must not be updated after the response is written.
+	ctx = context.WithValue(context.WithoutCancel(ctx), metaKey{}, (*Meta)(nil))
+
+	go func() {
+		defer c.endRefresh(key)
+
+		ctx, cancel := context.WithTimeout(ctx, backgroundRefreshTimeout)
+		defer cancel()
+
+		value, err := fetch(ctx)
+		if err != nil {
+			log.Printf("Background refresh of cache entry %s failed: %v", key, err)
+			return
+		}
+		store(c, key, value)
+	}()
+}
+
+// store encodes value and caches it under key.
+func store[T any](c *Cache, key string, value T) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		log.Printf("Error encoding cache entry %s: %v", key, err)
+		return
+	}
+	c.Set(key, data)
+}
@@ -0,0 +1,106 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// memoryStore is an in-memory Store, used to seed the cache with entries of a given age.
+type memoryStore struct {
+	mu      sync.Mutex
+	entries map[string]Entry
+}
+
+func (s *memoryStore) Load(key string) (Entry, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entry, ok := s.entries[key]
+	return entry, ok
+}
+
+func (s *memoryStore) Save(entry Entry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries[entry.Key] = entry
+	return nil
+}
+
+// cacheWithEntry creates a cache with a TTL of one minute holding value under key, stored age ago.
+func cacheWithEntry(t *testing.T, key string, value any, age time.Duration) *Cache {
+	t.Helper()
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := New(time.Minute, 10)
+	c.SetStore(&memoryStore{entries: map[string]Entry{key: {Key: key, StoredAt: time.Now().Add(-age), Value: data}}})
+	return c
+}
+
+func TestFetchServesStaleWhileRevalidating(t *testing.T) {
+	c := cacheWithEntry(t, "key", "old", 2*time.Minute)
+	c.SetStaleWhileRevalidate(time.Hour)
+	ctx, meta := WithMeta(context.Background())
+
+	refreshed := make(chan struct{})
+	value, err := Fetch(ctx, c, "key", func(context.Context) (string, error) {
+		defer close(refreshed)
+		return "new", nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if value != "old" || !meta.Stale() || meta.RevalidationFailed() {
+		t.Errorf("expected the stale value to be served immediately, got %q (stale %t)", value, meta.Stale())
+	}
+
+	// The value is refreshed in the background
+	select {
+	case <-refreshed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected a background refresh")
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		data, _, _ := c.Lookup("key")
+		if string(data) == `"new"` {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected the refreshed value to be cached, got %s", data)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestFetchRefetchesPastStaleWindow(t *testing.T) {
+	c := cacheWithEntry(t, "key", "old", 2*time.Hour)
+	c.SetStaleWhileRevalidate(time.Hour)
+
+	value, err := Fetch(context.Background(), c, "key", countingFetch("new", nil, new(int)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if value != "new" {
+		t.Errorf("expected a value past the stale-while-revalidate window to be refetched, got %q", value)
+	}
+}
+
+func TestFetchServesStaleWhenUpstreamFails(t *testing.T) {
+	c := cacheWithEntry(t, "key", "old", 24*time.Hour)
+	ctx, meta := WithMeta(context.Background())
+
+	value, err := Fetch(ctx, c, "key", countingFetch("", errors.New("upstream unavailable"), new(int)))
+	if err != nil {
+		t.Fatalf("expected the last known good value to be served, got %v", err)
+	}
+	if value != "old" || !meta.Stale() || !meta.RevalidationFailed() {
+		t.Errorf("expected the stale value after a failed revalidation, got %q (stale %t, failed %t)",
+			value, meta.Stale(), meta.RevalidationFailed())
+	}
+}
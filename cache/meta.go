@@ -0,0 +1,101 @@
+package cache
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Meta collects the freshness of every cached value used while serving a single request.
+type Meta struct {
+	mu                 sync.Mutex
+	oldest             time.Time     // Time the oldest value used was stored
+	expires            time.Time     // Time the first value used expires
+	window             time.Duration // Shortest stale-while-revalidate window of the values used
+	stale              bool          // Whether an expired value was served
+	revalidationFailed bool          // Whether an expired value was served because the upstream API failed
+}
+
+// metaKey is the context key under which the Meta of a request is stored.
+type metaKey struct{}
+
+// WithMeta returns a copy of ctx carrying a new Meta, together with that Meta.
+func WithMeta(ctx context.Context) (context.Context, *Meta) {
+	meta := &Meta{}
+	return context.WithValue(ctx, metaKey{}, meta), meta
+}
+
+// metaFrom returns the Meta attached to ctx, or nil if there is none.
+func metaFrom(ctx context.Context) *Meta {
+	meta, _ := ctx.Value(metaKey{}).(*Meta)
+	return meta
+}
+
+// record registers a value stored at storedAt that stays fresh for ttl, and can be served for window after expiring
+// while it is refreshed. stale marks a value served after expiring, revalidationFailed one served because the upstream API failed.
+func (m *Meta) record(storedAt time.Time, ttl, window time.Duration, stale, revalidationFailed bool) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.stale = m.stale || stale
+	m.revalidationFailed = m.revalidationFailed || revalidationFailed
+	if m.expires.IsZero() || window < m.window {
+		m.window = window
+	}
+
+	if m.oldest.IsZero() || storedAt.Before(m.oldest) {
+		m.oldest = storedAt
+	}
+	if expires := storedAt.Add(ttl); m.expires.IsZero() || expires.Before(m.expires) {
+		m.expires = expires
+	}
+}
+
+// Age returns how long ago the oldest value used was stored.
+func (m *Meta) Age() time.Duration {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.oldest.IsZero() {
+		return 0
+	}
+	return time.Since(m.oldest)
+}
+
+// MaxAge returns the freshness lifetime of the response, measured from the same
+// point in time as Age, so that MaxAge minus Age is the time until the first value used expires.
+// It returns false if no cached value was used.
+func (m *Meta) MaxAge() (time.Duration, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.expires.IsZero() {
+		return 0, false
+	}
+	return m.expires.Sub(m.oldest), true
+}
+
+// StaleWhileRevalidate returns how long after expiring the response can still be served while it is revalidated,
+// i.e. the shortest stale-while-revalidate window of the values used, or 0 if no cached value was used.
+func (m *Meta) StaleWhileRevalidate() time.Duration {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.window
+}
+
+// Stale reports whether any value used had expired.
+func (m *Meta) Stale() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.stale
+}
+
+// RevalidationFailed reports whether any expired value was used because the upstream API could not be reached.
+func (m *Meta) RevalidationFailed() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.revalidationFailed
+}
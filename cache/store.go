@@ -0,0 +1,89 @@
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Entry is a cached value together with the time it was fetched from upstream.
+type Entry struct {
+	Key      string          `json:"key"`
+	StoredAt time.Time       `json:"storedAt"`
+	Value    json.RawMessage `json:"value"`
+}
+
+// Store is a persistent backing store for the cache, letting entries survive restarts.
+type Store interface {
+	// Load returns the entry stored under key, regardless of its age.
+	Load(key string) (Entry, bool)
+
+	// Save persists the entry under its key, replacing any previous entry.
+	Save(entry Entry) error
+}
+
+// DiskStore is a Store keeping every entry as a JSON blob in a directory.
+type DiskStore struct {
+	dir string
+}
+
+// NewDiskStore creates a DiskStore in dir, creating the directory if it does not exist.
+//
+// Returns:
+//   - *DiskStore: The store.
+//   - error: An error if the directory cannot be created.
+func NewDiskStore(dir string) (*DiskStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create cache directory %s: %v", dir, err)
+	}
+	return &DiskStore{dir: dir}, nil
+}
+
+// Load reads the entry stored under key. Missing, unreadable or mismatching files are reported as absent.
+func (s *DiskStore) Load(key string) (Entry, bool) {
+	data, err := os.ReadFile(s.path(key))
+	if err != nil {
+		return Entry{}, false
+	}
+
+	var entry Entry
+	if err := json.Unmarshal(data, &entry); err != nil || entry.Key != key {
+		return Entry{}, false
+	}
+	return entry, true
+}
+
+// Save writes the entry to disk. The file is written to a temporary file first and then
+// renamed, so a crash never leaves a partially written entry behind.
+func (s *DiskStore) Save(entry Entry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to encode cache entry %s: %v", entry.Key, err)
+	}
+
+	tmp, err := os.CreateTemp(s.dir, "entry-*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create cache file: %v", err)
+	}
+	defer os.Remove(tmp.Name()) // No-op once the file has been renamed
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write cache entry %s: %v", entry.Key, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write cache entry %s: %v", entry.Key, err)
+	}
+	return os.Rename(tmp.Name(), s.path(entry.Key))
+}
+
+// path returns the file holding the entry for key. Keys are hashed, as they contain
+// user supplied country codes that must not be interpreted as file paths.
+func (s *DiskStore) path(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(s.dir, hex.EncodeToString(sum[:])+".json")
+}
@@ -0,0 +1,78 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestDiskStoreRoundTrip(t *testing.T) {
+	store, err := NewDiskStore(filepath.Join(t.TempDir(), "cache"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entry := Entry{Key: "restcountries:alpha:v2:NO", StoredAt: time.Now().Round(0), Value: []byte(`{"name":"Norway"}`)}
+	if err := store.Save(entry); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, ok := store.Load(entry.Key)
+	if !ok {
+		t.Fatal("expected the saved entry to be loaded")
+	}
+	if loaded.Key != entry.Key || !loaded.StoredAt.Equal(entry.StoredAt) || string(loaded.Value) != string(entry.Value) {
+		t.Errorf("expected %+v, got %+v", entry, loaded)
+	}
+	if _, ok := store.Load("countriesnow:cities:NO"); ok {
+		t.Error("expected a missing entry to be reported as absent")
+	}
+}
+
+func TestDiskStoreIgnoresCorruptFiles(t *testing.T) {
+	store, err := NewDiskStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(store.path("key"), []byte("{not json"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := store.Load("key"); ok {
+		t.Error("expected a corrupt entry to be reported as absent")
+	}
+}
+
+func TestCacheSurvivesRestart(t *testing.T) {
+	dir := t.TempDir()
+	upstreamErr := errors.New("upstream unavailable")
+
+	// Fill the cache of the first process
+	store, err := NewDiskStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	before := New(time.Hour, 10)
+	before.SetStore(store)
+	if _, err := Fetch(context.Background(), before, "key", countingFetch("Oslo", nil, new(int))); err != nil {
+		t.Fatal(err)
+	}
+
+	// A new process serves the persisted value without calling the upstream API
+	store, err = NewDiskStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	after := New(time.Hour, 10)
+	after.SetStore(store)
+	calls := 0
+	value, err := Fetch(context.Background(), after, "key", countingFetch("", upstreamErr, &calls))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if value != "Oslo" || calls != 0 {
+		t.Errorf("expected the persisted value without upstream calls, got %q after %d calls", value, calls)
+	}
+}
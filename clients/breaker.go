@@ -0,0 +1,155 @@
+package clients
+
+import (
+	"errors"
+	"log"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned instead of calling an upstream API whose circuit breaker is open.
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+// BreakerState is the state of a circuit breaker.
+type BreakerState int
+
+// Circuit breaker states
+const (
+	BreakerClosed   BreakerState = iota // Calls pass through
+	BreakerOpen                         // Calls fail fast until the cooldown has passed
+	BreakerHalfOpen                     // A single probe call is let through to test the upstream API
+)
+
+// String returns the name of the state.
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerClosed:
+		return "closed"
+	case BreakerOpen:
+		return "open"
+	case BreakerHalfOpen:
+		return "half-open"
+	default:
+		return "unknown"
+	}
+}
+
+// BreakerSettings configures when a circuit breaker opens and for how long.
+type BreakerSettings struct {
+	FailureThreshold int           // Consecutive failures opening the breaker
+	Cooldown         time.Duration // Time the breaker stays open before letting a probe call through
+}
+
+// DefaultBreakerSettings is used for upstream APIs without explicit settings.
+var DefaultBreakerSettings = BreakerSettings{
+	FailureThreshold: 5,
+	Cooldown:         30 * time.Second,
+}
+
+// Breaker is a circuit breaker guarding the calls to one upstream API.
+//
+// It opens after FailureThreshold consecutive failures, making calls fail fast with ErrCircuitOpen.
+// Once the cooldown has passed it turns half-open and lets a single probe call through:
+// if the probe succeeds the breaker closes, otherwise it opens again.
+type Breaker struct {
+	mu             sync.Mutex
+	name           string
+	settings       BreakerSettings
+	state          BreakerState
+	failures       int
+	lastTransition time.Time
+	probing        bool // Whether the half-open probe call is in flight
+}
+
+// BreakerSnapshot is the state of a circuit breaker at a point in time.
+type BreakerSnapshot struct {
+	Name           string
+	State          BreakerState
+	Failures       int
+	LastTransition time.Time
+}
+
+// NewBreaker creates a closed circuit breaker for the upstream API called name.
+func NewBreaker(name string, settings BreakerSettings) *Breaker {
+	if settings.FailureThreshold < 1 {
+		settings.FailureThreshold = 1
+	}
+	return &Breaker{
+		name:           name,
+		settings:       settings,
+		state:          BreakerClosed,
+		lastTransition: time.Now(),
+	}
+}
+
+// Allow reports whether a call may be made, returning ErrCircuitOpen if not.
+// Every allowed call must be followed by a call to Record with its outcome, or to Abandon.
+func (b *Breaker) Allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case BreakerOpen:
+		if time.Since(b.lastTransition) < b.settings.Cooldown {
+			return ErrCircuitOpen
+		}
+		b.transition(BreakerHalfOpen)
+		b.probing = true
+		return nil
+	case BreakerHalfOpen:
+		if b.probing {
+			return ErrCircuitOpen
+		}
+		b.probing = true
+		return nil
+	default:
+		return nil
+	}
+}
+
+// Record registers the outcome of an allowed call.
+func (b *Breaker) Record(success bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.probing = false
+	if success {
+		b.failures = 0
+		if b.state != BreakerClosed {
+			b.transition(BreakerClosed)
+		}
+		return
+	}
+
+	b.failures++
+	if b.state == BreakerHalfOpen || (b.state == BreakerClosed && b.failures >= b.settings.FailureThreshold) {
+		b.transition(BreakerOpen)
+	}
+}
+
+// Abandon releases an allowed call whose outcome is unknown, e.g. because the caller went away.
+func (b *Breaker) Abandon() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probing = false
+}
+
+// Snapshot returns the current state of the breaker.
+func (b *Breaker) Snapshot() BreakerSnapshot {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return BreakerSnapshot{
+		Name:           b.name,
+		State:          b.state,
+		Failures:       b.failures,
+		LastTransition: b.lastTransition,
+	}
+}
+
+// transition moves the breaker to state. The caller must hold b.mu.
+func (b *Breaker) transition(state BreakerState) {
+	log.Printf("%s circuit breaker %s -> %s", b.name, b.state, state)
+	b.state = state
+	b.lastTransition = time.Now()
+}
@@ -0,0 +1,140 @@
+package clients
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestBreakerOpensAfterConsecutiveFailures(t *testing.T) {
+	b := NewBreaker("test", BreakerSettings{FailureThreshold: 3, Cooldown: time.Hour})
+
+	for i := 0; i < 3; i++ {
+		if err := b.Allow(); err != nil {
+			t.Fatalf("expected call %d to be allowed, got %v", i+1, err)
+		}
+		b.Record(false)
+	}
+
+	if state := b.Snapshot().State; state != BreakerOpen {
+		t.Fatalf("expected the breaker to be open, got %s", state)
+	}
+	if err := b.Allow(); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("expected calls to fail fast while open, got %v", err)
+	}
+}
+
+func TestBreakerSuccessResetsFailures(t *testing.T) {
+	b := NewBreaker("test", BreakerSettings{FailureThreshold: 2, Cooldown: time.Hour})
+
+	for _, success := range []bool{false, true, false} {
+		if err := b.Allow(); err != nil {
+			t.Fatal(err)
+		}
+		b.Record(success)
+	}
+
+	if snapshot := b.Snapshot(); snapshot.State != BreakerClosed || snapshot.Failures != 1 {
+		t.Errorf("expected the breaker to stay closed with 1 failure, got %s with %d failures", snapshot.State, snapshot.Failures)
+	}
+}
+
+func TestBreakerHalfOpenProbe(t *testing.T) {
+	tests := []struct {
+		name    string
+		success bool
+		want    BreakerState
+	}{
+		{"successful probe closes", true, BreakerClosed},
+		{"failed probe reopens", false, BreakerOpen},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := NewBreaker("test", BreakerSettings{FailureThreshold: 1, Cooldown: 0})
+			if err := b.Allow(); err != nil {
+				t.Fatal(err)
+			}
+			b.Record(false)
+
+			// The cooldown has passed, so a single probe is let through
+			if err := b.Allow(); err != nil {
+				t.Fatalf("expected the probe to be allowed, got %v", err)
+			}
+			if state := b.Snapshot().State; state != BreakerHalfOpen {
+				t.Fatalf("expected the breaker to be half-open, got %s", state)
+			}
+			if err := b.Allow(); !errors.Is(err, ErrCircuitOpen) {
+				t.Fatalf("expected a second call to fail fast while probing, got %v", err)
+			}
+
+			b.Record(tt.success)
+			if state := b.Snapshot().State; state != tt.want {
+				t.Errorf("expected the breaker to be %s, got %s", tt.want, state)
+			}
+		})
+	}
+}
+
+func TestBreakerAbandonReleasesProbe(t *testing.T) {
+	b := NewBreaker("test", BreakerSettings{FailureThreshold: 1, Cooldown: 0})
+	b.Allow()
+	b.Record(false)
+	b.Allow()
+	b.Abandon()
+
+	if err := b.Allow(); err != nil {
+		t.Errorf("expected a new probe after abandoning the previous one, got %v", err)
+	}
+}
+
+func TestUpstreamShortCircuitsWhileOpen(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	breaker := NewBreaker("test", BreakerSettings{FailureThreshold: 2, Cooldown: time.Hour})
+	upstream := NewUpstream("test", RetryPolicy{Timeout: time.Second}, breaker, server.Client())
+
+	for i := 0; i < 2; i++ {
+		if resp, err := upstream.Do(context.Background(), http.MethodGet, server.URL, nil); err == nil {
+			resp.Body.Close()
+		}
+	}
+	if _, err := upstream.Do(context.Background(), http.MethodGet, server.URL, nil); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("expected the call to be short-circuited, got %v", err)
+	}
+	if n := requests.Load(); n != 2 {
+		t.Errorf("expected 2 requests to reach the upstream API, got %d", n)
+	}
+}
+
+func TestUpstreamIgnoresCallerDeadline(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select { // A healthy but slow upstream API
+		case <-r.Context().Done():
+		case <-time.After(200 * time.Millisecond):
+		}
+	}))
+	defer server.Close()
+
+	breaker := NewBreaker("test", BreakerSettings{FailureThreshold: 1, Cooldown: time.Hour})
+	upstream := NewUpstream("test", RetryPolicy{Timeout: time.Second}, breaker, server.Client())
+
+	for _, timeout := range []time.Duration{10 * time.Millisecond, 0} {
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		if _, err := upstream.Do(ctx, http.MethodGet, server.URL, nil); err == nil {
+			t.Fatal("expected the call to be cut off by the deadline of the caller")
+		}
+		cancel()
+	}
+	if state := breaker.Snapshot().State; state != BreakerClosed {
+		t.Errorf("expected the deadline of the caller not to open the breaker, got %s", state)
+	}
+}
@@ -0,0 +1,76 @@
+package clients
+
+import (
+	"context"
+	"github.com/SigurdRiseth/CountryInfoService/cache"
+	"strings"
+)
+
+// Cache key prefixes, one per upstream endpoint. The ISO code is appended to form the key.
+// The version in the country key is bumped whenever utils.RestCountriesFilter changes,
+// so that persisted entries lacking newly requested fields are not served.
+const (
+	restCountriesCountryKey   = "restcountries:alpha:v2:"
+	countriesNowCitiesKey     = "countriesnow:cities:"
+	countriesNowPopulationKey = "countriesnow:population:"
+	countriesNowStatesKey     = "countriesnow:states:"      // Followed by the country name
+	countriesNowStateCityKey  = "countriesnow:statecities:" // Followed by the country and state names
+)
+
+// CachedRestCountries is a RestCountriesClient serving responses from a cache before asking the wrapped client.
+type CachedRestCountries struct {
+	next  RestCountriesClient
+	cache *cache.Cache
+}
+
+// NewCachedRestCountries wraps next with the given cache.
+func NewCachedRestCountries(next RestCountriesClient, c *cache.Cache) *CachedRestCountries {
+	return &CachedRestCountries{next: next, cache: c}
+}
+
+// GetCountry returns the cached country details, fetching them on a miss.
+func (c *CachedRestCountries) GetCountry(ctx context.Context, isoCode string) (Country, error) {
+	return cache.Fetch(ctx, c.cache, restCountriesCountryKey+strings.ToUpper(isoCode), func(ctx context.Context) (Country, error) {
+		return c.next.GetCountry(ctx, isoCode)
+	})
+}
+
+// CachedCountriesNow is a CountriesNowClient serving responses from a cache before asking the wrapped client.
+type CachedCountriesNow struct {
+	next  CountriesNowClient
+	cache *cache.Cache
+}
+
+// NewCachedCountriesNow wraps next with the given cache.
+func NewCachedCountriesNow(next CountriesNowClient, c *cache.Cache) *CachedCountriesNow {
+	return &CachedCountriesNow{next: next, cache: c}
+}
+
+// GetCities returns the cached city list, fetching it on a miss.
+func (c *CachedCountriesNow) GetCities(ctx context.Context, isoCode string) ([]string, error) {
+	return cache.Fetch(ctx, c.cache, countriesNowCitiesKey+strings.ToUpper(isoCode), func(ctx context.Context) ([]string, error) {
+		return c.next.GetCities(ctx, isoCode)
+	})
+}
+
+// GetPopulation returns the cached population history, fetching it on a miss.
+func (c *CachedCountriesNow) GetPopulation(ctx context.Context, iso3 string) (PopulationData, error) {
+	return cache.Fetch(ctx, c.cache, countriesNowPopulationKey+strings.ToUpper(iso3), func(ctx context.Context) (PopulationData, error) {
+		return c.next.GetPopulation(ctx, iso3)
+	})
+}
+
+// GetStates returns the cached states, fetching them on a miss.
+func (c *CachedCountriesNow) GetStates(ctx context.Context, country string) ([]State, error) {
+	return cache.Fetch(ctx, c.cache, countriesNowStatesKey+strings.ToUpper(country), func(ctx context.Context) ([]State, error) {
+		return c.next.GetStates(ctx, country)
+	})
+}
+
+// GetStateCities returns the cached city list of the state, fetching it on a miss.
+func (c *CachedCountriesNow) GetStateCities(ctx context.Context, country, state string) ([]string, error) {
+	key := countriesNowStateCityKey + strings.ToUpper(country) + ":" + strings.ToUpper(state)
+	return cache.Fetch(ctx, c.cache, key, func(ctx context.Context) ([]string, error) {
+		return c.next.GetStateCities(ctx, country, state)
+	})
+}
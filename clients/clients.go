@@ -0,0 +1,110 @@
+// Package clients provides access to the upstream APIs the service builds on.
+//
+// Handlers depend on the RestCountriesClient and CountriesNowClient interfaces
+// rather than on net/http directly, so the live HTTP implementations can be
+// swapped for the in-memory fakes when testing.
+package clients
+
+import (
+	"context"
+	"github.com/SigurdRiseth/CountryInfoService/utils"
+)
+
+// RestCountriesClient retrieves country details from the RestCountries API.
+type RestCountriesClient interface {
+	// GetCountry returns the details of the country identified by the ISO2 code.
+	GetCountry(ctx context.Context, isoCode string) (Country, error)
+}
+
+// CountriesNowClient retrieves city and population data from the CountriesNow API.
+type CountriesNowClient interface {
+	// GetCities returns the cities of the country identified by the ISO2 code.
+	GetCities(ctx context.Context, isoCode string) ([]string, error)
+
+	// GetPopulation returns the population history of the country identified by the ISO3 code.
+	GetPopulation(ctx context.Context, iso3 string) (PopulationData, error)
+
+	// GetStates returns the states of the country with the given (common English) name.
+	GetStates(ctx context.Context, country string) ([]State, error)
+
+	// GetStateCities returns the cities of the named state of the country with the given (common English) name.
+	GetStateCities(ctx context.Context, country, state string) ([]string, error)
+}
+
+// Country represents the structure of the RestCountries API response
+type Country struct {
+	Name       Name                `json:"name"`
+	Capital    []string            `json:"capital"`
+	Languages  map[string]string   `json:"languages"`
+	Borders    []string            `json:"borders"`
+	Flag       string              `json:"flag"`
+	Population int                 `json:"population"`
+	Continents []string            `json:"continents"`
+	Currencies map[string]Currency `json:"currencies"`
+	Area       float64             `json:"area"`
+	Latlng     []float64           `json:"latlng"`
+	Timezones  []string            `json:"timezones"`
+	Idd        Idd                 `json:"idd"`
+	Tld        []string            `json:"tld"`
+	Subregion  string              `json:"subregion"`
+	UnMember   bool                `json:"unMember"`
+}
+
+// Currency represents a currency used in the country
+type Currency struct {
+	Name   string `json:"name"`
+	Symbol string `json:"symbol"`
+}
+
+// Idd represents the international direct dialing details of the country.
+// The calling codes of the country are the root followed by each of the suffixes.
+type Idd struct {
+	Root     string   `json:"root"`
+	Suffixes []string `json:"suffixes"`
+}
+
+// Name represents the naming details of the country
+type Name struct {
+	Common     string                `json:"common"`
+	Official   string                `json:"official"`
+	NativeName map[string]NativeName `json:"nativeName"`
+}
+
+// NativeName represents the native language details
+type NativeName struct {
+	Official string `json:"official"`
+	Common   string `json:"common"`
+}
+
+// PopulationData represents the population history of a country as returned by the CountriesNow API.
+type PopulationData struct {
+	Country          string            `json:"country"`
+	Code             string            `json:"code"`
+	Iso3             string            `json:"iso3"`
+	PopulationCounts []utils.YearValue `json:"populationCounts"`
+}
+
+// State represents a state (or other first-level subdivision) of a country as returned by the CountriesNow API.
+type State struct {
+	Name      string `json:"name"`
+	StateCode string `json:"state_code"`
+}
+
+// statesAPIResponse represents the envelope of the CountriesNow states endpoint.
+type statesAPIResponse struct {
+	Error bool   `json:"error"`
+	Msg   string `json:"msg"`
+	Data  struct {
+		Name   string  `json:"name"`
+		Iso2   string  `json:"iso2"`
+		Iso3   string  `json:"iso3"`
+		States []State `json:"states"`
+	} `json:"data"`
+}
+
+// populationAPIResponse represents the envelope of the CountriesNow population endpoint.
+type populationAPIResponse struct {
+	Error bool           `json:"error"`
+	Msg   string         `json:"msg"`
+	Data  PopulationData `json:"data"`
+}
@@ -0,0 +1,168 @@
+package clients
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/SigurdRiseth/CountryInfoService/utils"
+	"log"
+	"net/http"
+)
+
+// ErrStateNotFound is wrapped by the error reported for a state unknown to the CountriesNow API,
+// whether the API reports it as missing or it is not among the states of the country.
+var ErrStateNotFound = errors.New("state not found")
+
+// CountriesNowHTTP is the CountriesNowClient talking to the live CountriesNow API.
+type CountriesNowHTTP struct {
+	baseURL  string
+	upstream *Upstream
+}
+
+// NewCountriesNowHTTP creates a CountriesNowHTTP client for the API at baseURL.
+//
+// Parameters:
+//   - baseURL: The base URL of the API (e.g. utils.CountriesNowApiUrl).
+//   - upstream: The shared HTTP client applying timeouts and retries. If nil, DefaultRetryPolicy is used.
+//
+// Returns:
+//   - *CountriesNowHTTP: A client ready to be injected into the handlers.
+func NewCountriesNowHTTP(baseURL string, upstream *Upstream) *CountriesNowHTTP {
+	if upstream == nil {
+		upstream = NewUpstream("CountriesNow", DefaultRetryPolicy, nil, nil)
+	}
+	return &CountriesNowHTTP{baseURL: baseURL, upstream: upstream}
+}
+
+// GetCities fetches the list of cities for the given ISO2 code.
+//
+// Errors:
+//   - Returns a utils.APIError if the request fails, the response cannot be decoded or the API reports an error.
+func (c *CountriesNowHTTP) GetCities(ctx context.Context, isoCode string) ([]string, error) {
+	url := c.baseURL + utils.CountriesNowCityEndpoint
+	log.Println("Fetching city data from API:", url)
+
+	resp, err := c.post(ctx, url, map[string]string{"iso2": isoCode})
+	if err != nil {
+		return nil, transportError(utils.SourceCountriesNow, "failed to reach Countries-Now API for city data", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, statusError(utils.SourceCountriesNow, isoCode, resp.StatusCode)
+	}
+
+	var apiResponse utils.APIResponseString
+	if err := json.NewDecoder(resp.Body).Decode(&apiResponse); err != nil {
+		return nil, utils.NewUpstreamBadResponseError(utils.SourceCountriesNow, "failed to decode Countries-Now API response", err)
+	}
+
+	if apiResponse.Error {
+		return nil, utils.NewUpstreamBadResponseError(utils.SourceCountriesNow,
+			"Countries-Now API returned an error", errors.New(apiResponse.Message))
+	}
+
+	return apiResponse.Data, nil
+}
+
+// GetPopulation fetches the population history for the given ISO3 code.
+//
+// Errors:
+//   - Returns a utils.APIError if the request fails, the response cannot be decoded or the API reports an error.
+func (c *CountriesNowHTTP) GetPopulation(ctx context.Context, iso3 string) (PopulationData, error) {
+	url := c.baseURL + utils.CountriesNowPopulationEndpoint
+	log.Println("Fetching population data from API:", url)
+
+	resp, err := c.post(ctx, url, map[string]string{"iso3": iso3})
+	if err != nil {
+		return PopulationData{}, transportError(utils.SourceCountriesNow, "failed to reach Countries-Now API for population data", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return PopulationData{}, statusError(utils.SourceCountriesNow, iso3, resp.StatusCode)
+	}
+
+	var apiResponse populationAPIResponse
+	if err := json.NewDecoder(resp.Body).Decode(&apiResponse); err != nil {
+		return PopulationData{}, utils.NewUpstreamBadResponseError(utils.SourceCountriesNow, "failed to decode Countries-Now API response", err)
+	}
+
+	if apiResponse.Error {
+		return PopulationData{}, utils.NewUpstreamBadResponseError(utils.SourceCountriesNow,
+			"Countries-Now API returned an error", errors.New(apiResponse.Msg))
+	}
+
+	return apiResponse.Data, nil
+}
+
+// GetStates fetches the states of the country with the given name.
+//
+// Errors:
+//   - Returns a utils.APIError if the request fails, the response cannot be decoded or the API reports an error.
+func (c *CountriesNowHTTP) GetStates(ctx context.Context, country string) ([]State, error) {
+	url := c.baseURL + utils.CountriesNowStatesEndpoint
+	log.Println("Fetching state data from API:", url)
+
+	resp, err := c.post(ctx, url, map[string]string{"country": country})
+	if err != nil {
+		return nil, transportError(utils.SourceCountriesNow, "failed to reach Countries-Now API for state data", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, statusError(utils.SourceCountriesNow, country, resp.StatusCode)
+	}
+
+	var apiResponse statesAPIResponse
+	if err := json.NewDecoder(resp.Body).Decode(&apiResponse); err != nil {
+		return nil, utils.NewUpstreamBadResponseError(utils.SourceCountriesNow, "failed to decode Countries-Now API response", err)
+	}
+
+	if apiResponse.Error {
+		return nil, utils.NewUpstreamBadResponseError(utils.SourceCountriesNow,
+			"Countries-Now API returned an error", errors.New(apiResponse.Msg))
+	}
+
+	return apiResponse.Data.States, nil
+}
+
+// GetStateCities fetches the cities of the named state of the country with the given name.
+//
+// Errors:
+//   - Returns a utils.APIError if the request fails, the response cannot be decoded or the API reports an error.
+func (c *CountriesNowHTTP) GetStateCities(ctx context.Context, country, state string) ([]string, error) {
+	url := c.baseURL + utils.CountriesNowStateCitiesEndpoint
+	log.Println("Fetching state city data from API:", url)
+
+	resp, err := c.post(ctx, url, map[string]string{"country": country, "state": state})
+	if err != nil {
+		return nil, transportError(utils.SourceCountriesNow, "failed to reach Countries-Now API for state city data", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, stateStatusError(utils.SourceCountriesNow, country, state, resp.StatusCode)
+	}
+
+	var apiResponse utils.APIResponseString
+	if err := json.NewDecoder(resp.Body).Decode(&apiResponse); err != nil {
+		return nil, utils.NewUpstreamBadResponseError(utils.SourceCountriesNow, "failed to decode Countries-Now API response", err)
+	}
+
+	if apiResponse.Error {
+		return nil, utils.NewUpstreamBadResponseError(utils.SourceCountriesNow,
+			"Countries-Now API returned an error", errors.New(apiResponse.Message))
+	}
+
+	return apiResponse.Data, nil
+}
+
+// post sends payload as a JSON encoded POST request to url.
+func (c *CountriesNowHTTP) post(ctx context.Context, url string, payload map[string]string) (*http.Response, error) {
+	requestBody, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	return c.upstream.Do(ctx, http.MethodPost, url, requestBody)
+}
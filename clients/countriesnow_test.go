@@ -0,0 +1,30 @@
+package clients
+
+import (
+	"context"
+	"errors"
+	"github.com/SigurdRiseth/CountryInfoService/utils"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestGetStateCitiesReportsMissingState(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	breaker := NewBreaker("CountriesNow", BreakerSettings{FailureThreshold: 5, Cooldown: time.Minute})
+	client := NewCountriesNowHTTP(server.URL+"/", NewUpstream("CountriesNow", RetryPolicy{Timeout: time.Second}, breaker, server.Client()))
+
+	_, err := client.GetStateCities(context.Background(), "Nigeria", "Atlantis")
+	var apiErr *utils.APIError
+	if !errors.As(err, &apiErr) || apiErr.Kind != utils.KindNotFound {
+		t.Fatalf("expected a not found error, got %v", err)
+	}
+	if want := "state 'Atlantis' not found in Nigeria"; apiErr.Message != want {
+		t.Errorf("expected message %q, got %q", want, apiErr.Message)
+	}
+}
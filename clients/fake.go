@@ -0,0 +1,209 @@
+package clients
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/SigurdRiseth/CountryInfoService/utils"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// FakeRestCountries is an in-memory RestCountriesClient.
+// Countries are keyed by upper-case ISO2 code; unknown codes produce a not found error.
+type FakeRestCountries struct {
+	Countries map[string]Country
+	Err       error         // Returned by every call when set, to simulate an unavailable API
+	Delay     time.Duration // Added to every call, to simulate a slow API. Honours context cancellation
+}
+
+// NewFakeRestCountries creates an empty FakeRestCountries.
+func NewFakeRestCountries() *FakeRestCountries {
+	return &FakeRestCountries{
+		Countries: make(map[string]Country),
+	}
+}
+
+// LoadCountry reads a RestCountries country payload (e.g. tests/mockdata/info.json)
+// from path and registers it under the given ISO2 code.
+func (f *FakeRestCountries) LoadCountry(isoCode, path string) error {
+	var country Country
+	if err := loadFixture(path, &country); err != nil {
+		return err
+	}
+	f.Countries[strings.ToUpper(isoCode)] = country
+	return nil
+}
+
+// GetCountry returns the registered country for the ISO2 code.
+func (f *FakeRestCountries) GetCountry(ctx context.Context, isoCode string) (Country, error) {
+	if err := wait(ctx, f.Delay); err != nil {
+		return Country{}, err
+	}
+	if f.Err != nil {
+		return Country{}, f.Err
+	}
+	country, ok := f.Countries[strings.ToUpper(isoCode)]
+	if !ok {
+		return Country{}, statusError(utils.SourceRestCountries, isoCode, http.StatusNotFound)
+	}
+	return country, nil
+}
+
+// FakeCountriesNow is an in-memory CountriesNowClient.
+// Cities are keyed by upper-case ISO2 code, population data by upper-case ISO3 code,
+// states by upper-case country name and state cities by upper-case country and state names
+// joined by a colon; unknown codes and names produce a not found error.
+type FakeCountriesNow struct {
+	Cities      map[string][]string
+	Population  map[string]PopulationData
+	States      map[string][]State
+	StateCities map[string][]string
+	Err         error         // Returned by every call when set, to simulate an unavailable API
+	Delay       time.Duration // Added to every call, to simulate a slow API. Honours context cancellation
+}
+
+// NewFakeCountriesNow creates an empty FakeCountriesNow.
+func NewFakeCountriesNow() *FakeCountriesNow {
+	return &FakeCountriesNow{
+		Cities:      make(map[string][]string),
+		Population:  make(map[string]PopulationData),
+		States:      make(map[string][]State),
+		StateCities: make(map[string][]string),
+	}
+}
+
+// LoadCities reads a CountriesNow city payload (e.g. tests/mockdata/cities.json)
+// from path and registers it under the given ISO2 code.
+func (f *FakeCountriesNow) LoadCities(isoCode, path string) error {
+	var response utils.APIResponseString
+	if err := loadFixture(path, &response); err != nil {
+		return err
+	}
+	f.Cities[strings.ToUpper(isoCode)] = response.Data
+	return nil
+}
+
+// LoadPopulation reads a CountriesNow population payload (e.g. tests/mockdata/population.json)
+// from path and registers it under the given ISO3 code.
+func (f *FakeCountriesNow) LoadPopulation(iso3, path string) error {
+	var response populationAPIResponse
+	if err := loadFixture(path, &response); err != nil {
+		return err
+	}
+	f.Population[strings.ToUpper(iso3)] = response.Data
+	return nil
+}
+
+// LoadStates reads a CountriesNow state payload (e.g. tests/mockdata/states.json)
+// from path and registers it under the given country name.
+func (f *FakeCountriesNow) LoadStates(country, path string) error {
+	var response statesAPIResponse
+	if err := loadFixture(path, &response); err != nil {
+		return err
+	}
+	f.States[strings.ToUpper(country)] = response.Data.States
+	return nil
+}
+
+// LoadStateCities reads a CountriesNow state city payload (e.g. tests/mockdata/statecities.json)
+// from path and registers it under the given country and state names.
+func (f *FakeCountriesNow) LoadStateCities(country, state, path string) error {
+	var response utils.APIResponseString
+	if err := loadFixture(path, &response); err != nil {
+		return err
+	}
+	f.StateCities[strings.ToUpper(country+":"+state)] = response.Data
+	return nil
+}
+
+// GetCities returns the registered cities for the ISO2 code.
+func (f *FakeCountriesNow) GetCities(ctx context.Context, isoCode string) ([]string, error) {
+	if err := wait(ctx, f.Delay); err != nil {
+		return nil, err
+	}
+	if f.Err != nil {
+		return nil, f.Err
+	}
+	cities, ok := f.Cities[strings.ToUpper(isoCode)]
+	if !ok {
+		return nil, statusError(utils.SourceCountriesNow, isoCode, http.StatusNotFound)
+	}
+	return cities, nil
+}
+
+// GetPopulation returns the registered population data for the ISO3 code.
+func (f *FakeCountriesNow) GetPopulation(ctx context.Context, iso3 string) (PopulationData, error) {
+	if err := wait(ctx, f.Delay); err != nil {
+		return PopulationData{}, err
+	}
+	if f.Err != nil {
+		return PopulationData{}, f.Err
+	}
+	data, ok := f.Population[strings.ToUpper(iso3)]
+	if !ok {
+		return PopulationData{}, statusError(utils.SourceCountriesNow, iso3, http.StatusNotFound)
+	}
+	return data, nil
+}
+
+// GetStates returns the registered states for the country name.
+func (f *FakeCountriesNow) GetStates(ctx context.Context, country string) ([]State, error) {
+	if err := wait(ctx, f.Delay); err != nil {
+		return nil, err
+	}
+	if f.Err != nil {
+		return nil, f.Err
+	}
+	states, ok := f.States[strings.ToUpper(country)]
+	if !ok {
+		return nil, statusError(utils.SourceCountriesNow, country, http.StatusNotFound)
+	}
+	return states, nil
+}
+
+// GetStateCities returns the registered cities for the country and state names.
+func (f *FakeCountriesNow) GetStateCities(ctx context.Context, country, state string) ([]string, error) {
+	if err := wait(ctx, f.Delay); err != nil {
+		return nil, err
+	}
+	if f.Err != nil {
+		return nil, f.Err
+	}
+	cities, ok := f.StateCities[strings.ToUpper(country+":"+state)]
+	if !ok {
+		return nil, stateStatusError(utils.SourceCountriesNow, country, state, http.StatusNotFound)
+	}
+	return cities, nil
+}
+
+// wait sleeps for delay, returning early with the context error if ctx is done first.
+func wait(ctx context.Context, delay time.Duration) error {
+	if delay <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// loadFixture decodes the JSON file at path into v.
+func loadFixture(path string, v any) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open fixture %s: %v", path, err)
+	}
+	defer file.Close()
+
+	if err := json.NewDecoder(file).Decode(v); err != nil {
+		return fmt.Errorf("failed to decode fixture %s: %v", path, err)
+	}
+	return nil
+}
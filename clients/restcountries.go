@@ -0,0 +1,60 @@
+package clients
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/SigurdRiseth/CountryInfoService/utils"
+	"log"
+	"net/http"
+)
+
+// RestCountriesHTTP is the RestCountriesClient talking to the live RestCountries API.
+type RestCountriesHTTP struct {
+	baseURL  string
+	upstream *Upstream
+}
+
+// NewRestCountriesHTTP creates a RestCountriesHTTP client for the API at baseURL.
+//
+// Parameters:
+//   - baseURL: The URL of the alpha endpoint (e.g. utils.RestCountriesApiUrl).
+//   - upstream: The shared HTTP client applying timeouts and retries. If nil, DefaultRetryPolicy is used.
+//
+// Returns:
+//   - *RestCountriesHTTP: A client ready to be injected into the handlers.
+func NewRestCountriesHTTP(baseURL string, upstream *Upstream) *RestCountriesHTTP {
+	if upstream == nil {
+		upstream = NewUpstream("RestCountries", DefaultRetryPolicy, nil, nil)
+	}
+	return &RestCountriesHTTP{baseURL: baseURL, upstream: upstream}
+}
+
+// GetCountry fetches the country details for the given ISO2 code.
+//
+// Errors:
+//   - Returns a utils.APIError if the API cannot be reached, responds with a non-200 status code or the JSON cannot be decoded.
+func (c *RestCountriesHTTP) GetCountry(ctx context.Context, isoCode string) (Country, error) {
+	url := c.baseURL + isoCode + utils.RestCountriesFilter
+	log.Printf("Fetching data from API: %s for country code: %s", url, isoCode)
+
+	resp, err := c.upstream.Do(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		log.Printf("Error contacting API: %v", err)
+		return Country{}, transportError(utils.SourceRestCountries, "error contacting API", err)
+	}
+	defer resp.Body.Close()
+
+	// Ensure the response is successful
+	if resp.StatusCode != http.StatusOK {
+		log.Printf("Rest-Countries-API returned status code: %d", resp.StatusCode)
+		return Country{}, statusError(utils.SourceRestCountries, isoCode, resp.StatusCode)
+	}
+
+	var country Country
+	if err := json.NewDecoder(resp.Body).Decode(&country); err != nil {
+		log.Printf("Error decoding JSON: %v", err)
+		return Country{}, utils.NewUpstreamBadResponseError(utils.SourceRestCountries, "error decoding JSON", err)
+	}
+
+	return country, nil
+}
@@ -0,0 +1,256 @@
+package clients
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/SigurdRiseth/CountryInfoService/utils"
+	"io"
+	"log"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RetryPolicy configures the timeout and retries of the calls to one upstream API.
+type RetryPolicy struct {
+	Timeout    time.Duration // Timeout of a single attempt, including reading the response body
+	MaxRetries int           // Number of retries after the first attempt
+	BaseDelay  time.Duration // Backoff before the first retry, doubled for every further retry
+	MaxDelay   time.Duration // Upper bound of the backoff, and of an accepted Retry-After
+}
+
+// DefaultRetryPolicy is used for upstream APIs without an explicit policy.
+var DefaultRetryPolicy = RetryPolicy{
+	Timeout:    5 * time.Second,
+	MaxRetries: 2,
+	BaseDelay:  200 * time.Millisecond,
+	MaxDelay:   2 * time.Second,
+}
+
+// Upstream is the HTTP client shared by all calls to one upstream API.
+// It applies a per-attempt timeout, retries failed attempts with exponential backoff and jitter,
+// and fails fast while the circuit breaker of the upstream API is open.
+type Upstream struct {
+	name    string
+	client  *http.Client
+	policy  RetryPolicy
+	breaker *Breaker
+}
+
+// NewUpstream creates the HTTP client for the upstream API called name.
+//
+// Parameters:
+//   - name: The name of the upstream API, used in log messages.
+//   - policy: The timeout and retry policy of the calls.
+//   - breaker: The circuit breaker of the upstream API. If nil, calls are never short-circuited.
+//   - client: The HTTP client used for the requests. If nil, http.DefaultClient is used.
+//
+// Returns:
+//   - *Upstream: The client.
+func NewUpstream(name string, policy RetryPolicy, breaker *Breaker, client *http.Client) *Upstream {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &Upstream{name: name, client: client, policy: policy, breaker: breaker}
+}
+
+// Name returns the name of the upstream API.
+func (u *Upstream) Name() string {
+	return u.name
+}
+
+// Breaker returns the circuit breaker of the upstream API, or nil if it has none.
+func (u *Upstream) Breaker() *Breaker {
+	return u.breaker
+}
+
+// Do sends a request to the upstream API, retrying timeouts, network errors, 5xx and 429 responses.
+//
+// If the circuit breaker is open, ErrCircuitOpen is returned without calling the API. Otherwise the
+// outcome of the call, after all retries, is recorded by the breaker, unless ctx was done before the
+// call completed.
+//
+// The response body is read within the timeout of the attempt, so the returned body can be
+// consumed without holding a connection. A Retry-After header is honoured as the minimum delay
+// before the next attempt; if it exceeds the maximum delay of the policy, no further attempt is made.
+//
+// Parameters:
+//   - ctx: The context of the call. Retries stop as soon as it is done.
+//   - method: The HTTP method.
+//   - url: The URL to call.
+//   - body: The JSON request body, or nil.
+//
+// Returns:
+//   - *http.Response: The response of the last attempt.
+//   - error: The error of the last attempt, if it did not produce a response.
+func (u *Upstream) Do(ctx context.Context, method, url string, body []byte) (*http.Response, error) {
+	if u.breaker == nil {
+		return u.retry(ctx, method, url, body)
+	}
+
+	if err := u.breaker.Allow(); err != nil {
+		log.Printf("%s call to %s short-circuited: %v", u.name, url, err)
+		return nil, err
+	}
+
+	resp, err := u.retry(ctx, method, url, body)
+	if ctx.Err() != nil {
+		// The caller went away or its own deadline passed; that says nothing about the health of the upstream API,
+		// which is judged by the timeout of the attempts instead
+		u.breaker.Abandon()
+	} else {
+		u.breaker.Record(!retryable(resp, err))
+	}
+	return resp, err
+}
+
+// retry performs the request, retrying failed attempts according to the retry policy.
+func (u *Upstream) retry(ctx context.Context, method, url string, body []byte) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		resp, err := u.attempt(ctx, method, url, body)
+		if !retryable(resp, err) || attempt >= u.policy.MaxRetries || ctx.Err() != nil {
+			return resp, err
+		}
+
+		delay := u.backoff(attempt)
+		if retryAfter, ok := parseRetryAfter(resp); ok {
+			if retryAfter > u.policy.MaxDelay {
+				log.Printf("%s asked to retry after %s, giving up", u.name, retryAfter)
+				return resp, err
+			}
+			delay = max(delay, retryAfter)
+		}
+
+		log.Printf("%s call to %s failed (%s), retrying in %s (retry %d of %d)",
+			u.name, url, describe(resp, err), delay.Round(time.Millisecond), attempt+1, u.policy.MaxRetries)
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// attempt performs a single request, reading the full response body within the attempt timeout.
+func (u *Upstream) attempt(ctx context.Context, method, url string, body []byte) (*http.Response, error) {
+	if u.policy.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, u.policy.Timeout)
+		defer cancel()
+	}
+
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, url, reader)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := u.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(data))
+	return resp, nil
+}
+
+// backoff returns the delay before the given retry: a random duration up to
+// BaseDelay * 2^attempt, capped at MaxDelay ("full jitter").
+func (u *Upstream) backoff(attempt int) time.Duration {
+	ceiling := u.policy.BaseDelay << attempt
+	if ceiling <= 0 || ceiling > u.policy.MaxDelay { // <= 0 guards against overflow
+		ceiling = u.policy.MaxDelay
+	}
+	if ceiling <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int64N(int64(ceiling) + 1))
+}
+
+// retryable reports whether a failed attempt may succeed when repeated.
+func retryable(resp *http.Response, err error) bool {
+	if err != nil {
+		return !errors.Is(err, context.Canceled)
+	}
+	return resp.StatusCode >= http.StatusInternalServerError || resp.StatusCode == http.StatusTooManyRequests
+}
+
+// parseRetryAfter returns the delay requested by the Retry-After header, given either in seconds or as an HTTP date.
+func parseRetryAfter(resp *http.Response) (time.Duration, bool) {
+	if resp == nil {
+		return 0, false
+	}
+	value := resp.Header.Get("Retry-After")
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		return max(time.Until(date), 0), true
+	}
+	return 0, false
+}
+
+// describe summarizes the outcome of a failed attempt for logging.
+func describe(resp *http.Response, err error) string {
+	if err != nil {
+		return err.Error()
+	}
+	return "status " + strconv.Itoa(resp.StatusCode)
+}
+
+// transportError converts the error of a failed call to the upstream API into a utils.APIError.
+// Calls that ran out of time are reported as timeouts, all others (including calls short-circuited
+// by the circuit breaker) as the upstream API being unavailable.
+func transportError(upstream, message string, err error) error {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return utils.NewTimeoutError(upstream, message, err)
+	}
+	return utils.NewUpstreamUnavailableError(upstream, message, err)
+}
+
+// statusError converts a non-200 response of the upstream API about the country identified by code
+// into a utils.APIError.
+func statusError(upstream, code string, statusCode int) error {
+	err := fmt.Errorf("%s API returned error status code %d", upstream, statusCode)
+	switch {
+	case statusCode == http.StatusNotFound:
+		return utils.NewNotFoundError(fmt.Sprintf("country %s not found", code), err)
+	case statusCode == http.StatusBadRequest:
+		return utils.NewInvalidInputError(fmt.Sprintf("invalid country code %s", code), err)
+	case statusCode >= http.StatusInternalServerError || statusCode == http.StatusTooManyRequests:
+		return utils.NewUpstreamUnavailableError(upstream, "upstream API is unavailable", err)
+	default:
+		return utils.NewUpstreamBadResponseError(upstream, "unexpected response from upstream API", err)
+	}
+}
+
+// stateStatusError converts a non-200 response of the upstream API about the named state of the named country
+// into a utils.APIError. Unlike statusError, a 404 Not Found reports the state as missing (wrapping ErrStateNotFound),
+// since the country is known.
+func stateStatusError(upstream, country, state string, statusCode int) error {
+	if statusCode == http.StatusNotFound {
+		err := fmt.Errorf("%w: %s API returned error status code %d", ErrStateNotFound, upstream, statusCode)
+		return utils.NewNotFoundError(fmt.Sprintf("state '%s' not found in %s", state, country), err)
+	}
+	return statusError(upstream, country, statusCode)
+}
@@ -0,0 +1,175 @@
+package clients
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// testPolicy retries quickly, so that the tests do not wait for the backoff.
+var testPolicy = RetryPolicy{Timeout: time.Second, MaxRetries: 2, BaseDelay: time.Millisecond, MaxDelay: 10 * time.Millisecond}
+
+// flakyServer answers with the given status codes in turn, and with 200 OK once they are used up.
+// It counts the requests it receives in requests.
+func flakyServer(t *testing.T, requests *atomic.Int32, statusCodes ...int) *httptest.Server {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if n := int(requests.Add(1)); n <= len(statusCodes) {
+			w.WriteHeader(statusCodes[n-1])
+			return
+		}
+		w.Write([]byte(`{"error":false}`))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestUpstreamRetries(t *testing.T) {
+	tests := []struct {
+		name        string
+		statusCodes []int
+		wantStatus  int
+		wantCalls   int32
+	}{
+		{"server errors are retried", []int{http.StatusInternalServerError, http.StatusBadGateway}, http.StatusOK, 3},
+		{"rate limiting is retried", []int{http.StatusTooManyRequests}, http.StatusOK, 2},
+		{"retries are limited", []int{503, 503, 503, 503}, http.StatusServiceUnavailable, 3},
+		{"client errors are not retried", []int{http.StatusNotFound}, http.StatusNotFound, 1},
+		{"bad requests are not retried", []int{http.StatusBadRequest}, http.StatusBadRequest, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requests atomic.Int32
+			server := flakyServer(t, &requests, tt.statusCodes...)
+			upstream := NewUpstream("test", testPolicy, nil, server.Client())
+
+			resp, err := upstream.Do(context.Background(), http.MethodGet, server.URL, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.StatusCode != tt.wantStatus || requests.Load() != tt.wantCalls {
+				t.Errorf("expected status %d after %d requests, got %d after %d", tt.wantStatus, tt.wantCalls, resp.StatusCode, requests.Load())
+			}
+		})
+	}
+}
+
+func TestUpstreamRetriesTimeouts(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) == 1 {
+			select { // Hang until the attempt times out
+			case <-r.Context().Done():
+			case <-time.After(time.Second):
+			}
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	policy := testPolicy
+	policy.Timeout = 20 * time.Millisecond
+	resp, err := NewUpstream("test", policy, nil, server.Client()).Do(context.Background(), http.MethodGet, server.URL, nil)
+	if err != nil {
+		t.Fatalf("expected the timed out attempt to be retried, got %v", err)
+	}
+	if resp.StatusCode != http.StatusOK || requests.Load() != 2 {
+		t.Errorf("expected status 200 after 2 requests, got %d after %d", resp.StatusCode, requests.Load())
+	}
+}
+
+func TestUpstreamGivesUpOnLongRetryAfter(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.Header().Set("Retry-After", "60")
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	start := time.Now()
+	resp, err := NewUpstream("test", testPolicy, nil, server.Client()).Do(context.Background(), http.MethodGet, server.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusServiceUnavailable || requests.Load() != 1 {
+		t.Errorf("expected to give up after the first request, got status %d after %d requests", resp.StatusCode, requests.Load())
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("expected not to wait for the Retry-After, took %s", elapsed)
+	}
+}
+
+func TestUpstreamStopsRetryingWhenCancelled(t *testing.T) {
+	var requests atomic.Int32
+	server := flakyServer(t, &requests, 503, 503, 503)
+
+	policy := testPolicy
+	policy.BaseDelay, policy.MaxDelay = time.Hour, time.Hour
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	if _, err := NewUpstream("test", policy, nil, server.Client()).Do(ctx, http.MethodGet, server.URL, nil); err == nil {
+		t.Fatal("expected the context error")
+	}
+	if requests.Load() != 1 {
+		t.Errorf("expected no retry after the deadline, got %d requests", requests.Load())
+	}
+}
+
+func TestBackoffIsCapped(t *testing.T) {
+	upstream := NewUpstream("test", RetryPolicy{BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second}, nil, nil)
+
+	for attempt := 0; attempt < 70; attempt++ {
+		ceiling := min(100*time.Millisecond<<attempt, time.Second)
+		if attempt >= 4 {
+			ceiling = time.Second // Also once the shift overflows
+		}
+		for range 20 {
+			if delay := upstream.backoff(attempt); delay < 0 || delay > ceiling {
+				t.Fatalf("retry %d: expected a delay up to %s, got %s", attempt, ceiling, delay)
+			}
+		}
+	}
+
+	if delay := NewUpstream("test", RetryPolicy{}, nil, nil).backoff(3); delay != 0 {
+		t.Errorf("expected no delay without a base delay, got %s", delay)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	tests := []struct {
+		value string
+		want  time.Duration
+		ok    bool
+	}{
+		{"", 0, false},
+		{"3", 3 * time.Second, true},
+		{"-3", 0, false},
+		{"soon", 0, false},
+		{time.Now().Add(-time.Hour).UTC().Format(http.TimeFormat), 0, true},
+	}
+	for _, tt := range tests {
+		resp := &http.Response{Header: http.Header{}}
+		if tt.value != "" {
+			resp.Header.Set("Retry-After", tt.value)
+		}
+		if got, ok := parseRetryAfter(resp); got != tt.want || ok != tt.ok {
+			t.Errorf("Retry-After %q: expected %s (%t), got %s (%t)", tt.value, tt.want, tt.ok, got, ok)
+		}
+	}
+
+	future := time.Now().Add(time.Minute).UTC().Format(http.TimeFormat)
+	resp := &http.Response{Header: http.Header{"Retry-After": {future}}}
+	if got, ok := parseRetryAfter(resp); !ok || got <= 50*time.Second || got > time.Minute {
+		t.Errorf("expected about a minute for an HTTP date, got %s (%t)", got, ok)
+	}
+	if _, ok := parseRetryAfter(nil); ok {
+		t.Error("expected no delay without a response")
+	}
+}
@@ -1,11 +1,29 @@
 package config
 
 import (
+	"github.com/SigurdRiseth/CountryInfoService/clients"
 	"github.com/joho/godotenv"
 	"log"
 	"os"
+	"strconv"
+	"sync"
+	"time"
 )
 
+// Default values used when the corresponding environment variable is not set.
+const (
+	defaultPort           = "8080"
+	defaultRequestTimeout = 15 * time.Second
+	defaultCacheTTL       = 24 * time.Hour
+	defaultCacheMaxSize   = 1000
+	minRequestTimeout     = time.Millisecond // Shorter request timeouts, including 0, would time out every request
+
+	defaultCacheStaleWhileRevalidate = time.Hour
+)
+
+// loadEnvOnce ensures the .env file is only loaded once, however many settings are read.
+var loadEnvOnce sync.Once
+
 // loadEnvVariables loads environment variables from a .env file located in the parent directory.
 // If there is an error loading the .env file, it logs the error.
 func loadEnvVariables() {
@@ -14,21 +32,133 @@ func loadEnvVariables() {
 	}
 }
 
+// getEnv returns the value of the environment variable key, loading the .env file first when running locally.
+func getEnv(key string) string {
+	loadEnvOnce.Do(func() {
+		// Load .env only if running locally
+		if os.Getenv("RENDER") == "" { // Render automatically sets this variable
+			log.Println("Running locally. Loading environment variables from .env file")
+			loadEnvVariables()
+		}
+	})
+	return os.Getenv(key)
+}
+
 // GetPort retrieves the port from the environment variable "PORT" or defaults to 8080 if the variable is not set.
 //
 // Returns:
 // - A string representing the port number.
 func GetPort() string {
-	// Load .env only if running locally
-	if os.Getenv("RENDER") == "" { // Render automatically sets this variable
-		log.Println("Running locally. Loading environment variables from .env file")
-		loadEnvVariables()
-	}
-
-	port := os.Getenv("PORT")
+	port := getEnv("PORT")
 	if port == "" {
-		log.Println("$PORT has not been set. Defaulting to 8080")
-		port = "8080"
+		log.Println("$PORT has not been set. Defaulting to " + defaultPort)
+		port = defaultPort
 	}
 	return port
 }
+
+// GetRequestTimeout retrieves the overall deadline of a request, including all upstream calls and retries,
+// from the environment variable "REQUEST_TIMEOUT" (e.g. "15s").
+//
+// Returns:
+// - The request timeout, defaulting to 15 seconds if the variable is unset, invalid or not positive.
+func GetRequestTimeout() time.Duration {
+	return getDuration("REQUEST_TIMEOUT", defaultRequestTimeout, minRequestTimeout)
+}
+
+// GetCacheTTL retrieves how long upstream responses are cached from the environment variable "CACHE_TTL".
+// The value is a Go duration (e.g. "30m" or "24h"). A TTL of 0 disables the cache.
+//
+// Returns:
+// - The cache TTL, defaulting to 24 hours if the variable is unset or invalid.
+func GetCacheTTL() time.Duration {
+	return getDuration("CACHE_TTL", defaultCacheTTL, 0)
+}
+
+// GetCacheStaleWhileRevalidate retrieves how long an expired upstream response is still served,
+// while being refreshed in the background, from the environment variable "CACHE_STALE_WHILE_REVALIDATE".
+// Past this window responses are refreshed before answering, falling back to the expired response if that fails.
+//
+// Returns:
+// - The stale-while-revalidate window, defaulting to 1 hour if the variable is unset or invalid.
+func GetCacheStaleWhileRevalidate() time.Duration {
+	return getDuration("CACHE_STALE_WHILE_REVALIDATE", defaultCacheStaleWhileRevalidate, 0)
+}
+
+// GetCacheMaxSize retrieves the maximum number of cached upstream responses from the environment variable "CACHE_MAX_SIZE".
+//
+// Returns:
+// - The maximum cache size, defaulting to 1000 if the variable is unset or invalid.
+func GetCacheMaxSize() int {
+	return getInt("CACHE_MAX_SIZE", defaultCacheMaxSize, 1)
+}
+
+// GetCacheDir retrieves the directory of the persistent response cache from the environment variable "CACHE_DIR".
+//
+// Returns:
+// - The cache directory, or an empty string if upstream responses should only be cached in memory.
+func GetCacheDir() string {
+	return getEnv("CACHE_DIR")
+}
+
+// GetRetryPolicy retrieves the timeout and retry policy of the upstream API identified by prefix
+// (e.g. "RESTCOUNTRIES" or "COUNTRIESNOW") from the environment variables:
+//   - <prefix>_TIMEOUT: Timeout of a single attempt (e.g. "5s").
+//   - <prefix>_MAX_RETRIES: Number of retries after a failed attempt.
+//   - <prefix>_RETRY_BASE_DELAY: Backoff before the first retry, doubled for every further retry.
+//   - <prefix>_RETRY_MAX_DELAY: Upper bound of the backoff and of an accepted Retry-After header.
+//
+// Returns:
+// - The retry policy, using clients.DefaultRetryPolicy for unset or invalid variables.
+func GetRetryPolicy(prefix string) clients.RetryPolicy {
+	def := clients.DefaultRetryPolicy
+	return clients.RetryPolicy{
+		Timeout:    getDuration(prefix+"_TIMEOUT", def.Timeout, 0),
+		MaxRetries: getInt(prefix+"_MAX_RETRIES", def.MaxRetries, 0),
+		BaseDelay:  getDuration(prefix+"_RETRY_BASE_DELAY", def.BaseDelay, 0),
+		MaxDelay:   getDuration(prefix+"_RETRY_MAX_DELAY", def.MaxDelay, 0),
+	}
+}
+
+// GetBreakerSettings retrieves the circuit breaker settings of the upstream API identified by prefix
+// (e.g. "RESTCOUNTRIES" or "COUNTRIESNOW") from the environment variables:
+//   - <prefix>_BREAKER_FAILURE_THRESHOLD: Consecutive failed calls opening the breaker.
+//   - <prefix>_BREAKER_COOLDOWN: Time the breaker stays open before a probe call is let through (e.g. "30s").
+//
+// Returns:
+// - The breaker settings, using clients.DefaultBreakerSettings for unset or invalid variables.
+func GetBreakerSettings(prefix string) clients.BreakerSettings {
+	def := clients.DefaultBreakerSettings
+	return clients.BreakerSettings{
+		FailureThreshold: getInt(prefix+"_BREAKER_FAILURE_THRESHOLD", def.FailureThreshold, 1),
+		Cooldown:         getDuration(prefix+"_BREAKER_COOLDOWN", def.Cooldown, 0),
+	}
+}
+
+// getDuration parses the environment variable key as a time.Duration of at least min, falling back to def.
+func getDuration(key string, def, min time.Duration) time.Duration {
+	value := getEnv(key)
+	if value == "" {
+		return def
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil || duration < min {
+		log.Printf("Invalid $%s value %q. Defaulting to %s", key, value, def)
+		return def
+	}
+	return duration
+}
+
+// getInt parses the environment variable key as an integer of at least min, falling back to def.
+func getInt(key string, def, min int) int {
+	value := getEnv(key)
+	if value == "" {
+		return def
+	}
+	number, err := strconv.Atoi(value)
+	if err != nil || number < min {
+		log.Printf("Invalid $%s value %q. Defaulting to %d", key, value, def)
+		return def
+	}
+	return number
+}
@@ -0,0 +1,36 @@
+package config
+
+import (
+	"testing"
+	"time"
+)
+
+func TestGetRequestTimeout(t *testing.T) {
+	t.Setenv("RENDER", "true") // Skip loading the .env file
+
+	tests := []struct {
+		value string
+		want  time.Duration
+	}{
+		{"", defaultRequestTimeout},
+		{"2s", 2 * time.Second},
+		{"0s", defaultRequestTimeout},
+		{"-1s", defaultRequestTimeout},
+		{"soon", defaultRequestTimeout},
+	}
+	for _, tt := range tests {
+		t.Setenv("REQUEST_TIMEOUT", tt.value)
+		if got := GetRequestTimeout(); got != tt.want {
+			t.Errorf("REQUEST_TIMEOUT=%q: expected %s, got %s", tt.value, tt.want, got)
+		}
+	}
+}
+
+func TestGetCacheTTLAcceptsZero(t *testing.T) {
+	t.Setenv("RENDER", "true")
+	t.Setenv("CACHE_TTL", "0s")
+
+	if ttl := GetCacheTTL(); ttl != 0 {
+		t.Errorf("expected a TTL of 0 to disable the cache, got %s", ttl)
+	}
+}
@@ -3,4 +3,7 @@ module github.com/SigurdRiseth/CountryInfoService
 go 1.23
 
 // Dependencies
-require github.com/joho/godotenv v1.5.1
+require (
+	github.com/joho/godotenv v1.5.1
+	golang.org/x/text v0.21.0
+)
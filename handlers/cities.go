@@ -0,0 +1,280 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/SigurdRiseth/CountryInfoService/cache"
+	"github.com/SigurdRiseth/CountryInfoService/iso"
+	"github.com/SigurdRiseth/CountryInfoService/utils"
+	"golang.org/x/text/collate"
+	"golang.org/x/text/language"
+	"log"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Sort orders of city lists
+const (
+	sortAscending  = "asc"
+	sortDescending = "desc"
+)
+
+// HandleCities processes requests to browse the cities of a country page by page.
+//
+// Request Parameters:
+//   - "country" (path parameter): The code or name of the country (see resolveCountry).
+//   - "offset" (query parameter, optional): The number of cities to skip, 0 by default.
+//   - "limit" (query parameter, optional): The number of cities per page, from 1 to utils.MaxCityPageSize.
+//     Defaults to utils.DefaultCityPageSize.
+//   - "prefix" (query parameter, optional): Only returns the cities starting with the prefix, ignoring case.
+//   - "sort" (query parameter, optional): The alphabetical order of the cities, "asc" (default) or "desc".
+//
+// Response:
+//   - Returns a JSON response containing a utils.CityPage: the cities of the page, the total number of
+//     (matching) cities, and the link to the next page if there is one.
+//     The cities are the same deduplicated and sorted cities as listed by /info (see getCities).
+//
+// HTTP Status Codes:
+//   - 200 OK: Successfully retrieved the page, possibly without any cities if the offset is past the end.
+//   - 400 Bad Request: Unknown country, or invalid offset, limit or sort order.
+//   - 404 Not Found: No cities exist for the country.
+//   - 502 Bad Gateway / 503 Service Unavailable: The CountriesNow API answered unexpectedly or cannot be reached.
+//   - 504 Gateway Timeout: The request deadline passed before the CountriesNow API answered.
+//
+// Example Usage:
+//
+//	GET /countryinfo/v1/cities/US?offset=50&limit=50 -> Retrieves the cities 51 to 100 of the United States.
+//	GET /countryinfo/v1/cities/NO?prefix=sk -> Retrieves the first cities of Norway starting with "Sk".
+//
+// Returns a utils.APIError if fetching the cities fails, leaving the error response to the caller.
+func HandleCities(w http.ResponseWriter, r *http.Request) error {
+	w.Header().Set("Content-Type", "application/json")
+
+	// Extract and validate the query parameters
+	query := r.URL.Query()
+	offset, err := parseCityPageParam("offset", query.Get("offset"), 0, 0, -1)
+	if err != nil {
+		return err
+	}
+	limit, err := parseCityPageParam("limit", query.Get("limit"), utils.DefaultCityPageSize, 1, utils.MaxCityPageSize)
+	if err != nil {
+		return err
+	}
+	descending, err := parseSortOrder(query.Get("sort"))
+	if err != nil {
+		return err
+	}
+	prefix := query.Get("prefix")
+
+	// Resolve the country to its ISO2 code, rejecting unknown countries before calling the upstream APIs
+	country, err := resolveCountry(r.PathValue("country"))
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Fetching cities for country code: %s with offset %d, limit %d and prefix '%s'", country.Alpha2, offset, limit, prefix)
+
+	// Fetch the cities, keeping track of the cached values used
+	ctx, meta := cache.WithMeta(r.Context())
+	timing := newServerTiming()
+	start := time.Now()
+	cities, err := getCities(ctx, country, "", descending)
+	timing.add(utils.SourceCountriesNow, time.Since(start))
+	w.Header().Set("Server-Timing", timing.String())
+	if deadlineExceeded(ctx) && err != nil {
+		return errRequestTimeout
+	}
+	if err != nil {
+		return err
+	}
+
+	// Filter by prefix, then cut out the requested page
+	if prefix != "" {
+		cities = filterCitiesByPrefix(cities, prefix)
+	}
+	from := min(offset, len(cities))
+	to := from + min(limit, len(cities)-from)
+	page := utils.CityPage{
+		Country: country.Alpha2,
+		Total:   len(cities),
+		Offset:  offset,
+		Limit:   limit,
+		Cities:  cities[from:to],
+	}
+	if to < len(cities) {
+		next := r.URL.Query()
+		next.Set("offset", strconv.Itoa(to))
+		next.Set("limit", strconv.Itoa(limit))
+		page.Next = (&url.URL{Path: r.URL.Path, RawQuery: next.Encode()}).String()
+	}
+
+	setCacheHeaders(w, meta)
+	w.WriteHeader(http.StatusOK)
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false) // Keep the '&' of the next link readable
+	return encoder.Encode(utils.APIResponse{
+		Error:   false,
+		Message: "Cities retrieved successfully",
+		Data:    page,
+		Stale:   meta.Stale(),
+	})
+}
+
+// getCities retrieves the cities of a country, or of one of its states, through the injected CountriesNow client,
+// deduplicated and sorted (see sortCities). It is shared by the info and cities endpoints,
+// so that both list the same cities in the same order.
+//
+// Parameters:
+//   - ctx (context.Context): The context of the incoming request, passed on to the upstream calls.
+//   - country (iso.Country): The country.
+//   - state (string): The name or code of a state to list the cities of (see resolveState), or empty for all cities.
+//   - descending (bool): Whether to sort in descending instead of ascending order.
+//
+// Returns:
+//   - []string: The sorted cities.
+//   - error: A utils.APIError if the state does not exist or the cities cannot be retrieved.
+func getCities(ctx context.Context, country iso.Country, state string, descending bool) ([]string, error) {
+	var cities []string
+	if state == "" {
+		var err error
+		if cities, err = CountriesNow.GetCities(ctx, country.Alpha2); err != nil {
+			return nil, err
+		}
+	} else {
+		resolved, err := resolveState(ctx, country, state)
+		if err != nil {
+			return nil, err
+		}
+		if cities, err = CountriesNow.GetStateCities(ctx, country.Name, resolved.Name); err != nil {
+			return nil, err
+		}
+	}
+	return sortCities(cities, country.Alpha2, descending), nil
+}
+
+// filterCitiesByPrefix returns the cities starting with the prefix, ignoring case.
+//
+// Example Usage:
+//
+//	filterCitiesByPrefix([]string{"Oslo", "Bergen", "Osøyro"}, "os") // ["Oslo", "Osøyro"]
+func filterCitiesByPrefix(cities []string, prefix string) []string {
+	prefix = strings.ToLower(strings.TrimSpace(prefix))
+	filtered := []string{}
+	for _, city := range cities {
+		if strings.HasPrefix(strings.ToLower(city), prefix) {
+			filtered = append(filtered, city)
+		}
+	}
+	return filtered
+}
+
+// parseCityPageParam parses a pagination parameter of the cities endpoint.
+//
+// Parameters:
+//   - name (string): The name of the parameter, for the error message.
+//   - value (string): The value of the parameter; the default is used if empty.
+//   - def (int): The default value.
+//   - lower (int): The lowest value allowed.
+//   - upper (int): The highest value allowed, or -1 if unbounded.
+//
+// Returns:
+//   - int: The value of the parameter.
+//   - error: An invalid input utils.APIError if the value is not a number within the bounds.
+func parseCityPageParam(name, value string, def, lower, upper int) (int, error) {
+	if value == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < lower || (upper >= 0 && n > upper) {
+		expected := fmt.Sprintf("a number of at least %d", lower)
+		if upper >= 0 {
+			expected = fmt.Sprintf("a number from %d to %d", lower, upper)
+		}
+		return 0, utils.NewInvalidInputError(fmt.Sprintf("invalid %s '%s', expected %s", name, value, expected), err)
+	}
+	return n, nil
+}
+
+// parseSortOrder parses the sort order of a city list, defaulting to ascending if not provided.
+//
+// Parameters:
+//   - order (string): The sort order, "asc" or "desc".
+//
+// Returns:
+//   - bool: True if the cities are to be sorted in descending order.
+//   - error: An invalid input utils.APIError if the sort order is not recognized.
+func parseSortOrder(order string) (bool, error) {
+	switch strings.ToLower(order) {
+	case "", sortAscending:
+		return false, nil
+	case sortDescending:
+		return true, nil
+	default:
+		return false, utils.NewInvalidInputError(
+			fmt.Sprintf("invalid sort order '%s', expected '%s' or '%s'", order, sortAscending, sortDescending), nil)
+	}
+}
+
+// sortCities removes duplicate and blank city names, and sorts the cities in the alphabetical order
+// of the main language of the country, so that e.g. "Ålesund" comes after "Zeta" for Norway.
+//
+// Parameters:
+//   - cities ([]string): The city names as returned by the CountriesNow API.
+//   - isoCode (string): The ISO2 code of the country, selecting the collation.
+//   - descending (bool): Whether to sort in descending instead of ascending order.
+//
+// Returns:
+//   - []string: A new slice with the deduplicated and sorted cities.
+//
+// Example Usage:
+//
+//	cities := sortCities([]string{"Oslo", "Ålesund", "Bergen", "Oslo"}, "NO", false) // ["Bergen", "Oslo", "Ålesund"]
+func sortCities(cities []string, isoCode string, descending bool) []string {
+	seen := make(map[string]bool, len(cities))
+	sorted := make([]string, 0, len(cities))
+	for _, city := range cities {
+		city = strings.TrimSpace(city)
+		if city == "" || seen[city] {
+			continue
+		}
+		seen[city] = true
+		sorted = append(sorted, city)
+	}
+
+	collator := collate.New(collationLanguage(isoCode))
+	collator.SortStrings(sorted)
+	if descending {
+		slices.Reverse(sorted)
+	}
+	return sorted
+}
+
+// collationLanguage returns the language whose alphabetical order is used for the cities of a country:
+// the most likely language spoken in the country, as inferred from the CLDR likely subtags.
+//
+// Norwegian Bokmål has no collation rules of its own in the CLDR data of golang.org/x/text,
+// so Norwegian Nynorsk, sharing the Norwegian alphabet, is used for it instead.
+func collationLanguage(isoCode string) language.Tag {
+	region, err := language.ParseRegion(isoCode)
+	if err != nil {
+		return language.Und
+	}
+	tag, err := language.Compose(language.Und, region)
+	if err != nil {
+		return language.Und
+	}
+	base, _ := tag.Base()
+	if base.String() == "nb" || base.String() == "no" {
+		base = language.MustParseBase("nn")
+	}
+	tag, err = language.Compose(base, region)
+	if err != nil {
+		return language.Und
+	}
+	return tag
+}
@@ -0,0 +1,87 @@
+package handler
+
+import (
+	"github.com/SigurdRiseth/CountryInfoService/utils"
+	"net/http"
+	"slices"
+	"testing"
+)
+
+// getCityPage serves the cities endpoint for Nigeria with the given query and decodes the page.
+func getCityPage(t *testing.T, query string) utils.CityPage {
+	t.Helper()
+
+	w, err := serve(HandleCities, "/countryinfo/v1/cities/ng"+query, map[string]string{"country": "ng"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, w.Code)
+	}
+	var page utils.CityPage
+	decodeResponse(t, w, &page)
+	return page
+}
+
+func TestHandleCitiesPages(t *testing.T) {
+	useFakeClients(t)
+
+	all := getCityPage(t, "?limit=500")
+	if all.Total != len(all.Cities) || all.Next != "" {
+		t.Fatalf("expected every city on a single page, got %d of %d and next link %q", len(all.Cities), all.Total, all.Next)
+	}
+	if all.Total != 85 {
+		t.Errorf("expected the 87 cities of the mock data without the duplicate Abraka and Warri, got %d", all.Total)
+	}
+
+	first := getCityPage(t, "?limit=10")
+	if first.Total != all.Total || first.Offset != 0 || first.Limit != 10 || !slices.Equal(first.Cities, all.Cities[:10]) {
+		t.Errorf("unexpected first page %+v", first)
+	}
+	if want := "/countryinfo/v1/cities/ng?limit=10&offset=10"; first.Next != want {
+		t.Errorf("expected next link %q, got %q", want, first.Next)
+	}
+
+	last := getCityPage(t, "?offset=80&limit=10")
+	if !slices.Equal(last.Cities, all.Cities[80:]) || last.Total != all.Total || last.Next != "" {
+		t.Errorf("expected the last cities without a next link, got %+v", last)
+	}
+}
+
+func TestHandleCitiesOffsetPastEnd(t *testing.T) {
+	useFakeClients(t)
+
+	page := getCityPage(t, "?offset=1000")
+	if len(page.Cities) != 0 || page.Cities == nil || page.Next != "" || page.Offset != 1000 || page.Total == 0 {
+		t.Errorf("expected an empty page with the total number of cities, got %+v", page)
+	}
+}
+
+func TestHandleCitiesPrefix(t *testing.T) {
+	useFakeClients(t)
+
+	page := getCityPage(t, "?prefix=AB&limit=2&sort=desc")
+	if page.Total != 5 || !slices.Equal(page.Cities, []string{"Abuja", "Abraka"}) {
+		t.Errorf("expected 5 cities starting with 'Ab', from Abuja down, got %+v", page)
+	}
+	if want := "/countryinfo/v1/cities/ng?limit=2&offset=2&prefix=AB&sort=desc"; page.Next != want {
+		t.Errorf("expected the next link to keep the filter, got %q", page.Next)
+	}
+
+	if none := getCityPage(t, "?prefix=zz"); none.Total != 0 || len(none.Cities) != 0 || none.Next != "" {
+		t.Errorf("expected no cities starting with 'zz', got %+v", none)
+	}
+}
+
+func TestHandleCitiesInvalidParams(t *testing.T) {
+	useFakeClients(t)
+
+	for _, query := range []string{"?limit=0", "?limit=501", "?limit=x", "?offset=-1", "?sort=up"} {
+		_, err := serve(HandleCities, "/countryinfo/v1/cities/ng"+query, map[string]string{"country": "ng"})
+		assertErrorKind(t, err, utils.KindInvalidInput)
+	}
+
+	if page := getCityPage(t, ""); page.Limit != utils.DefaultCityPageSize || len(page.Cities) != utils.DefaultCityPageSize {
+		t.Errorf("expected a default page of %d cities, got %d", utils.DefaultCityPageSize, len(page.Cities))
+	}
+}
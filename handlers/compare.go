@@ -0,0 +1,260 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/SigurdRiseth/CountryInfoService/cache"
+	"github.com/SigurdRiseth/CountryInfoService/iso"
+	"github.com/SigurdRiseth/CountryInfoService/utils"
+	"log"
+	"math"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// HandleCompare processes requests to compare the population of several countries side by side.
+//
+// Request Parameters:
+//   - "codes" (query parameter): A comma-separated list of 2 to utils.MaxCompareCountries countries,
+//     by code or name (see resolveCountry). Countries listed twice are compared once.
+//   - "limit" (query parameter, optional): The years to compare, as a comma-separated list of year ranges (see parseYearLimit).
+//   - "fill" (query parameter, optional): How to fill in the years missing from the data, "none" (default),
+//     "linear" or "previous" (see fillMissingYears).
+//
+// Response:
+//   - Returns a JSON response containing a utils.PopulationComparison: the population history of every country,
+//     and the populations aligned by year with the share of every country in the combined population and the rank
+//     of its growth. Years with data for only some of the countries list the countries without data as missing.
+//
+// HTTP Status Codes:
+//   - 200 OK: Successfully compared the populations.
+//   - 400 Bad Request: Missing, unknown or too many countries, or an invalid year range or fill method.
+//   - 404 Not Found: No population data exists for one of the countries.
+//   - 502 Bad Gateway / 503 Service Unavailable: The CountriesNow API answered unexpectedly or cannot be reached.
+//   - 504 Gateway Timeout: The request deadline passed before the CountriesNow API answered.
+//
+// Example Usage:
+//
+//	GET /countryinfo/v1/population/compare?codes=NO,SE,DK,FI&limit=2000-2020 -> Compares the Nordic countries from 2000 to 2020.
+//
+// Returns a utils.APIError if fetching any of the populations fails, leaving the error response to the caller.
+func HandleCompare(w http.ResponseWriter, r *http.Request) error {
+	w.Header().Set("Content-Type", "application/json")
+
+	// Extract and validate the query parameters
+	query := r.URL.Query()
+	fill, err := parseFill(query.Get("fill"))
+	if err != nil {
+		return err
+	}
+	ranges, err := parseYearLimit(query.Get("limit"))
+	if err != nil {
+		return err
+	}
+	countries, err := parseCompareCodes(query.Get("codes"))
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Comparing population data for %d countries with limit %s", len(countries), query.Get("limit"))
+
+	// Fetch every population history concurrently, keeping track of the cached values used
+	ctx, meta := cache.WithMeta(r.Context())
+	histories, err := getPopulations(ctx, countries)
+	if deadlineExceeded(ctx) && err != nil {
+		return errRequestTimeout
+	}
+	if err != nil {
+		return err
+	}
+
+	comparison := utils.PopulationComparison{Countries: make([]utils.ComparedPopulation, 0, len(countries))}
+	growths := make([]map[int]float64, len(countries))
+	for i, country := range countries {
+		resolved := resolveYearRanges(ranges, histories[i]) // Relative ranges end in the last year of data of each country
+		filled := fillMissingYears(histories[i], fill, resolved)
+		growths[i] = yearlyGrowth(filled) // Before filtering, so that the first year selected has a growth too
+		values := filterByYearRanges(filled, resolved)
+		comparison.Countries = append(comparison.Countries, utils.ComparedPopulation{
+			Code:   country.Alpha2,
+			Name:   country.Name,
+			Mean:   meanPopulation(values),
+			Values: values,
+		})
+	}
+	comparison.Years = compareByYear(comparison.Countries, growths)
+
+	setCacheHeaders(w, meta)
+	w.WriteHeader(http.StatusOK)
+	return json.NewEncoder(w).Encode(utils.APIResponse{
+		Error:   false,
+		Message: "Population comparison retrieved successfully",
+		Data:    comparison,
+		Stale:   meta.Stale(),
+	})
+}
+
+// parseCompareCodes resolves the comma-separated countries of the codes parameter.
+//
+// Parameters:
+//   - codes (string): The value of the codes parameter.
+//
+// Returns:
+//   - []iso.Country: The distinct countries, in the order first listed.
+//   - error: An invalid input utils.APIError if a country is unknown, or if there are fewer than 2
+//     or more than utils.MaxCompareCountries distinct countries.
+func parseCompareCodes(codes string) ([]iso.Country, error) {
+	var countries []iso.Country
+	for _, code := range strings.Split(codes, ",") {
+		if strings.TrimSpace(code) == "" {
+			continue
+		}
+		country, err := resolveCountry(code)
+		if err != nil {
+			return nil, err
+		}
+		if !slices.ContainsFunc(countries, func(c iso.Country) bool { return c.Alpha2 == country.Alpha2 }) {
+			countries = append(countries, country)
+		}
+	}
+	if len(countries) < 2 || len(countries) > utils.MaxCompareCountries {
+		return nil, utils.NewInvalidInputError(fmt.Sprintf("invalid codes '%s', expected a comma-separated list of 2 to %d countries",
+			codes, utils.MaxCompareCountries), nil)
+	}
+	return countries, nil
+}
+
+// getPopulations fetches the population histories of the countries concurrently through the injected CountriesNow client.
+// If any of them cannot be fetched, the other requests are cancelled, as they would be discarded anyway.
+//
+// Parameters:
+//   - ctx (context.Context): The context of the incoming request, passed on to the upstream calls.
+//   - countries ([]iso.Country): The countries.
+//
+// Returns:
+//   - [][]utils.YearValue: The population history of every country, in the order of the countries.
+//   - error: The first error encountered, if any.
+func getPopulations(ctx context.Context, countries []iso.Country) ([][]utils.YearValue, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg        sync.WaitGroup
+		errOnce   sync.Once
+		firstErr  error
+		histories = make([][]utils.YearValue, len(countries))
+	)
+	for i, country := range countries {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			populationData, err := CountriesNow.GetPopulation(ctx, country.Alpha3)
+			if err != nil {
+				errOnce.Do(func() {
+					firstErr = err
+					cancel()
+				})
+				return
+			}
+			histories[i] = populationData.PopulationCounts
+		}()
+	}
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	return histories, nil
+}
+
+// yearlyGrowth computes the annual growth of a population history from every year to the next year with data, in percent.
+// Growth over a gap of several years is the compound annual growth rate over the gap, so that countries with
+// gaps of different lengths can be ranked against each other. Years following a zero population have no growth.
+//
+// Example Usage:
+//
+//	growth := yearlyGrowth([]utils.YearValue{{Year: 2000, Value: 100}, {Year: 2001, Value: 110}, {Year: 2003, Value: 121}})
+//	// {2001: 10, 2003: 4.88}
+func yearlyGrowth(values []utils.YearValue) map[int]float64 {
+	byYear := slices.Clone(values)
+	slices.SortFunc(byYear, func(a, b utils.YearValue) int { return a.Year - b.Year })
+
+	growth := make(map[int]float64, len(byYear))
+	for i := 1; i < len(byYear); i++ {
+		previous, current := byYear[i-1], byYear[i]
+		if years := current.Year - previous.Year; previous.Value != 0 && years > 0 {
+			ratio := float64(current.Value) / float64(previous.Value)
+			growth[current.Year] = round2((math.Pow(ratio, 1/float64(years)) - 1) * 100)
+		}
+	}
+	return growth
+}
+
+// compareByYear aligns the population histories of the compared countries by year.
+//
+// Parameters:
+//   - countries ([]utils.ComparedPopulation): The population histories of the countries.
+//   - growths ([]map[int]float64): The yearly growth of every country (see yearlyGrowth), in the same order.
+//
+// Returns:
+//   - []utils.YearComparison: A comparison for every year with data for any of the countries, sorted by year.
+//     The shares are relative to the combined population of the countries with data for the year, and the
+//     countries with the same growth share the same rank.
+func compareByYear(countries []utils.ComparedPopulation, growths []map[int]float64) []utils.YearComparison {
+	valuesByYear := make(map[int][]*utils.YearValue)
+	for i, country := range countries {
+		for _, v := range country.Values {
+			if valuesByYear[v.Year] == nil {
+				valuesByYear[v.Year] = make([]*utils.YearValue, len(countries))
+			}
+			valuesByYear[v.Year][i] = &v
+		}
+	}
+
+	years := make([]utils.YearComparison, 0, len(valuesByYear))
+	for year, values := range valuesByYear {
+		comparison := utils.YearComparison{Year: year, Countries: []utils.CountryYearValue{}}
+		for i, v := range values {
+			if v == nil {
+				comparison.Missing = append(comparison.Missing, countries[i].Code)
+				continue
+			}
+			comparison.Total += v.Value
+			entry := utils.CountryYearValue{Code: countries[i].Code, Value: v.Value, Estimated: v.Estimated}
+			if growth, ok := growths[i][year]; ok {
+				entry.Growth = &growth
+			}
+			comparison.Countries = append(comparison.Countries, entry)
+		}
+		for i := range comparison.Countries {
+			if comparison.Total > 0 {
+				comparison.Countries[i].Share = round2(float64(comparison.Countries[i].Value) / float64(comparison.Total) * 100)
+			}
+		}
+		rankGrowth(comparison.Countries)
+		years = append(years, comparison)
+	}
+
+	slices.SortFunc(years, func(a, b utils.YearComparison) int { return a.Year - b.Year })
+	return years
+}
+
+// rankGrowth ranks the countries with a growth from the fastest (1) to the slowest growing.
+// Countries with the same growth share the same rank, and the next rank is skipped (e.g. 1, 2, 2, 4).
+func rankGrowth(countries []utils.CountryYearValue) {
+	for i := range countries {
+		if countries[i].Growth == nil {
+			continue
+		}
+		rank := 1
+		for _, other := range countries {
+			if other.Growth != nil && *other.Growth > *countries[i].Growth {
+				rank++
+			}
+		}
+		countries[i].GrowthRank = &rank
+	}
+}
@@ -0,0 +1,110 @@
+package handler
+
+import (
+	"github.com/SigurdRiseth/CountryInfoService/clients"
+	"github.com/SigurdRiseth/CountryInfoService/utils"
+	"net/http"
+	"testing"
+)
+
+func TestYearlyGrowth(t *testing.T) {
+	growth := yearlyGrowth([]utils.YearValue{
+		{Year: 2003, Value: 121},
+		{Year: 2000, Value: 100},
+		{Year: 2001, Value: 110},
+		{Year: 2004, Value: 0},
+		{Year: 2005, Value: 10},
+	})
+
+	want := map[int]float64{2001: 10, 2003: 4.88, 2004: -100}
+	if len(growth) != len(want) {
+		t.Fatalf("expected growth %v, got %v", want, growth)
+	}
+	for year, g := range want {
+		if growth[year] != g {
+			t.Errorf("expected growth %v in %d, got %v", g, year, growth[year])
+		}
+	}
+}
+
+func TestRankGrowth(t *testing.T) {
+	growth := func(g float64) *float64 { return &g }
+	countries := []utils.CountryYearValue{
+		{Code: "A", Growth: growth(1)},
+		{Code: "B", Growth: growth(3)},
+		{Code: "C"},
+		{Code: "D", Growth: growth(1)},
+		{Code: "E", Growth: growth(0.5)},
+	}
+	rankGrowth(countries)
+
+	for i, want := range []int{2, 1, 0, 2, 4} {
+		rank := countries[i].GrowthRank
+		if want == 0 && rank != nil || want != 0 && (rank == nil || *rank != want) {
+			t.Errorf("%s: expected rank %d, got %v", countries[i].Code, want, rank)
+		}
+	}
+}
+
+func TestHandleCompareAnnualizesGaps(t *testing.T) {
+	_, countriesNow := useFakeClients(t)
+
+	// Sweden grows 21% over two years, i.e. 10% a year, so Denmark growing 15% in a year grows faster
+	countriesNow.Population["SWE"] = clients.PopulationData{Iso3: "SWE", PopulationCounts: []utils.YearValue{
+		{Year: 2000, Value: 100}, {Year: 2002, Value: 121},
+	}}
+	countriesNow.Population["DNK"] = clients.PopulationData{Iso3: "DNK", PopulationCounts: []utils.YearValue{
+		{Year: 2001, Value: 100}, {Year: 2002, Value: 115},
+	}}
+
+	w, err := serve(HandleCompare, "/countryinfo/v1/population/compare?codes=se,dk", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, w.Code)
+	}
+
+	var comparison utils.PopulationComparison
+	decodeResponse(t, w, &comparison)
+	if len(comparison.Years) != 3 {
+		t.Fatalf("expected the years 2000 to 2002, got %+v", comparison.Years)
+	}
+	if missing := comparison.Years[0].Missing; len(missing) != 1 || missing[0] != "DK" {
+		t.Errorf("expected Denmark to be missing in 2000, got %v", missing)
+	}
+
+	sweden, denmark := comparison.Years[2].Countries[0], comparison.Years[2].Countries[1]
+	if *sweden.Growth != 10 || *sweden.GrowthRank != 2 || *denmark.Growth != 15 || *denmark.GrowthRank != 1 {
+		t.Errorf("expected Sweden to grow 10%% a year and rank below Denmark, got %+v and %+v", sweden, denmark)
+	}
+	if comparison.Years[2].Total != 236 || sweden.Share != 51.27 {
+		t.Errorf("unexpected total %d and share %v", comparison.Years[2].Total, sweden.Share)
+	}
+}
+
+func TestHandleCompareInvalidCodes(t *testing.T) {
+	useFakeClients(t)
+
+	for _, codes := range []string{"no", "no,nor", "no,xx", ""} {
+		_, err := serve(HandleCompare, "/countryinfo/v1/population/compare?codes="+codes, nil)
+		assertErrorKind(t, err, utils.KindInvalidInput)
+	}
+}
+
+func TestHandleCompareRelativeRangeWithFill(t *testing.T) {
+	useFakeClients(t)
+
+	w, err := serve(HandleCompare, "/countryinfo/v1/population/compare?codes=ng,no&limit=last:2,2020&fill=previous", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var comparison utils.PopulationComparison
+	decodeResponse(t, w, &comparison)
+	for _, country := range comparison.Countries {
+		if len(country.Values) != 3 || country.Values[0].Year != 2017 || country.Values[2].Year != 2020 {
+			t.Errorf("%s: expected the years 2017, 2018 and the estimated 2020, got %+v", country.Code, country.Values)
+		}
+	}
+}
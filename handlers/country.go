@@ -0,0 +1,35 @@
+package handler
+
+import (
+	"fmt"
+	"github.com/SigurdRiseth/CountryInfoService/iso"
+	"github.com/SigurdRiseth/CountryInfoService/utils"
+)
+
+// resolveCountry resolves the country identified in a request, shared by every endpoint taking a country,
+// using the embedded ISO 3166-1 table so that no upstream API is called for unknown countries.
+//
+// Parameters:
+//   - input (string): The country from the request path: an alpha-2, alpha-3 or numeric code,
+//     or the common, official or native name of the country, in any case (e.g., "no", "NOR", "578", "Norge").
+//
+// Returns:
+//   - iso.Country: The country, identifying it by its alpha-2 and alpha-3 codes (e.g., "NO" and "NOR").
+//   - error: An invalid input utils.APIError if no country is identified by input,
+//     suggesting the countries the client may have meant.
+//
+// Example Usage:
+//
+//	country, err := resolveCountry("norwy") // err suggests NO (Norway)
+func resolveCountry(input string) (iso.Country, error) {
+	if country, ok := iso.Resolve(input); ok {
+		return country, nil
+	}
+
+	apiErr := utils.NewInvalidInputError(fmt.Sprintf(
+		"unknown country '%s', expected an ISO 3166-1 alpha-2, alpha-3 or numeric code or a country name", input), nil)
+	for _, country := range iso.Suggest(input, utils.MaxSuggestions) {
+		apiErr.Suggestions = append(apiErr.Suggestions, utils.Suggestion{Code: country.Alpha2, Name: country.Name})
+	}
+	return iso.Country{}, apiErr
+}
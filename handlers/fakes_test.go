@@ -0,0 +1,82 @@
+package handler
+
+import (
+	"encoding/json"
+	"github.com/SigurdRiseth/CountryInfoService/clients"
+	"github.com/SigurdRiseth/CountryInfoService/utils"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// mockdata is the directory of the upstream API payloads the fake clients are loaded from.
+const mockdata = "../tests/mockdata/"
+
+// useFakeClients injects fake upstream clients loaded from tests/mockdata for the duration of the test:
+// Norway (info.json) from RestCountries, and the cities (cities.json), population (population.json),
+// states (states.json) and Lagos cities (statecities.json) of Nigeria from CountriesNow.
+// The population is also registered under Norway, so that both countries have a population history.
+func useFakeClients(t *testing.T) (*clients.FakeRestCountries, *clients.FakeCountriesNow) {
+	t.Helper()
+
+	restCountries := clients.NewFakeRestCountries()
+	countriesNow := clients.NewFakeCountriesNow()
+	for _, err := range []error{
+		restCountries.LoadCountry("NO", mockdata+"info.json"),
+		countriesNow.LoadCities("NG", mockdata+"cities.json"),
+		countriesNow.LoadPopulation("NGA", mockdata+"population.json"),
+		countriesNow.LoadPopulation("NOR", mockdata+"population.json"),
+		countriesNow.LoadStates("Nigeria", mockdata+"states.json"),
+		countriesNow.LoadStateCities("Nigeria", "Lagos", mockdata+"statecities.json"),
+	} {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	previousRestCountries, previousCountriesNow := RestCountries, CountriesNow
+	RestCountries, CountriesNow = restCountries, countriesNow
+	t.Cleanup(func() { RestCountries, CountriesNow = previousRestCountries, previousCountriesNow })
+	return restCountries, countriesNow
+}
+
+// serve calls the handler with a GET request for target, setting the given path values.
+// It returns the recorded response and the error returned by the handler.
+func serve(handler func(http.ResponseWriter, *http.Request) error, target string, pathValues map[string]string) (*httptest.ResponseRecorder, error) {
+	r := httptest.NewRequest(http.MethodGet, target, nil)
+	for name, value := range pathValues {
+		r.SetPathValue(name, value)
+	}
+	w := httptest.NewRecorder()
+	return w, handler(w, r)
+}
+
+// decodeResponse decodes the utils.APIResponse in the recorded response, decoding its data into data.
+func decodeResponse(t *testing.T, w *httptest.ResponseRecorder, data any) utils.APIResponse {
+	t.Helper()
+
+	var response struct {
+		utils.APIResponse
+		Data json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("failed to decode response %q: %v", w.Body.String(), err)
+	}
+	if err := json.Unmarshal(response.Data, data); err != nil {
+		t.Fatalf("failed to decode data %s: %v", response.Data, err)
+	}
+	return response.APIResponse
+}
+
+// assertErrorKind fails the test unless err is a utils.APIError of the given kind.
+func assertErrorKind(t *testing.T, err error, kind utils.ErrorKind) {
+	t.Helper()
+
+	apiErr, ok := err.(*utils.APIError)
+	if !ok {
+		t.Fatalf("expected a utils.APIError of kind %s, got %v", kind.Code(), err)
+	}
+	if apiErr.Kind != kind {
+		t.Fatalf("expected an error of kind %s, got %s: %v", kind.Code(), apiErr.Kind.Code(), err)
+	}
+}
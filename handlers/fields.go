@@ -0,0 +1,210 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/SigurdRiseth/CountryInfoService/utils"
+	"slices"
+	"strings"
+)
+
+// infoFieldSources maps every field of utils.CountryInfo that can be selected with the fields parameter
+// to the upstream API providing it, so that upstream APIs providing no selected field are not called.
+var infoFieldSources = map[string]string{
+	"name":       utils.SourceRestCountries,
+	"continents": utils.SourceRestCountries,
+	"population": utils.SourceRestCountries,
+	"languages":  utils.SourceRestCountries,
+	"borders":    utils.SourceRestCountries,
+	"flag":       utils.SourceRestCountries,
+	"capital":    utils.SourceRestCountries,
+	"cities":     utils.SourceCountriesNow,
+
+	// Optional fields, see optionalInfoFields
+	"officialname": utils.SourceRestCountries,
+	"nativenames":  utils.SourceRestCountries,
+	"currencies":   utils.SourceRestCountries,
+	"area":         utils.SourceRestCountries,
+	"latlng":       utils.SourceRestCountries,
+	"timezones":    utils.SourceRestCountries,
+	"callingcodes": utils.SourceRestCountries,
+	"tlds":         utils.SourceRestCountries,
+	"subregion":    utils.SourceRestCountries,
+	"unmember":     utils.SourceRestCountries,
+}
+
+// optionalInfoFields are the fields of utils.CountryInfo left out of the response unless selected,
+// either with the include parameter or by naming them in the fields parameter.
+// This keeps the default response unchanged for existing clients.
+var optionalInfoFields = map[string]bool{
+	"officialname": true,
+	"nativenames":  true,
+	"currencies":   true,
+	"area":         true,
+	"latlng":       true,
+	"timezones":    true,
+	"callingcodes": true,
+	"tlds":         true,
+	"subregion":    true,
+	"unmember":     true,
+}
+
+// fieldSet is a set of selected response fields. A nil fieldSet selects every field except the optional ones.
+type fieldSet map[string]bool
+
+// has reports whether the field is selected.
+func (f fieldSet) has(field string) bool {
+	if f == nil {
+		return !optionalInfoFields[field]
+	}
+	return f[field]
+}
+
+// hasAllDefaults reports whether every non-optional field is selected.
+func (f fieldSet) hasAllDefaults() bool {
+	for field := range infoFieldSources {
+		if !optionalInfoFields[field] && !f.has(field) {
+			return false
+		}
+	}
+	return true
+}
+
+// needs reports whether any selected field is provided by the upstream API.
+func (f fieldSet) needs(source string) bool {
+	for field, fieldSource := range infoFieldSources {
+		if fieldSource == source && f.has(field) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseFields parses a comma-separated list of utils.CountryInfo fields (e.g. "name,flag,capital").
+//
+// Parameters:
+//   - fieldsString (string): The value of the fields parameter; empty selects every non-optional field.
+//
+// Returns:
+//   - fieldSet: The selected fields, or nil if every non-optional field is selected.
+//   - error: An invalid input utils.APIError naming the first unknown field, if any.
+func parseFields(fieldsString string) (fieldSet, error) {
+	if strings.TrimSpace(fieldsString) == "" {
+		return nil, nil
+	}
+
+	fields := make(fieldSet)
+	for _, field := range strings.Split(fieldsString, ",") {
+		field = strings.ToLower(strings.TrimSpace(field))
+		if field == "" {
+			continue
+		}
+		if _, ok := infoFieldSources[field]; !ok {
+			return nil, utils.NewInvalidInputError(
+				fmt.Sprintf("unknown field '%s', expected a comma-separated list of %s", field, knownFields()), nil)
+		}
+		fields[field] = true
+	}
+	if len(fields) == 0 {
+		return nil, nil
+	}
+	return fields, nil
+}
+
+// parseInclude parses a comma-separated list of optional utils.CountryInfo fields (e.g. "currencies,area")
+// and adds them to the selected fields.
+//
+// Parameters:
+//   - includeString (string): The value of the include parameter; empty includes no optional field.
+//   - fields (fieldSet): The fields selected with the fields parameter, or nil for every non-optional field.
+//
+// Returns:
+//   - fieldSet: The selected fields with the included ones added, or nil if fields is nil and nothing is included.
+//   - error: An invalid input utils.APIError naming the first field that is not optional, if any.
+//
+// Example Usage:
+//
+//	fields, err := parseInclude("currencies,area", nil) // Every non-optional field, plus currencies and area
+func parseInclude(includeString string, fields fieldSet) (fieldSet, error) {
+	included := make(fieldSet)
+	for _, field := range strings.Split(includeString, ",") {
+		field = strings.ToLower(strings.TrimSpace(field))
+		if field == "" {
+			continue
+		}
+		if !optionalInfoFields[field] {
+			return nil, utils.NewInvalidInputError(
+				fmt.Sprintf("unknown optional field '%s', expected a comma-separated list of %s", field, optionalFields()), nil)
+		}
+		included[field] = true
+	}
+	if len(included) == 0 {
+		return fields, nil
+	}
+
+	// Start from the non-optional fields if no fields were selected explicitly
+	if fields == nil {
+		fields = make(fieldSet)
+		for field := range infoFieldSources {
+			if !optionalInfoFields[field] {
+				fields[field] = true
+			}
+		}
+	}
+	for field := range included {
+		fields[field] = true
+	}
+	return fields, nil
+}
+
+// knownFields returns the selectable fields as a comma-separated list, for error messages.
+func knownFields() string {
+	fields := make([]string, 0, len(infoFieldSources))
+	for field := range infoFieldSources {
+		fields = append(fields, field)
+	}
+	slices.Sort(fields)
+	return strings.Join(fields, ", ")
+}
+
+// optionalFields returns the optional fields as a comma-separated list, for error messages.
+func optionalFields() string {
+	fields := make([]string, 0, len(optionalInfoFields))
+	for field := range optionalInfoFields {
+		fields = append(fields, field)
+	}
+	slices.Sort(fields)
+	return strings.Join(fields, ", ")
+}
+
+// selectFields trims the JSON representation of v down to the selected fields.
+//
+// Parameters:
+//   - v (any): The value to trim, encoding to a JSON object (e.g. a utils.CountryInfo).
+//   - fields (fieldSet): The fields to keep; nil keeps every field (optional fields are expected to be left empty).
+//
+// Returns:
+//   - any: v itself if every non-optional field is selected, otherwise a map holding the JSON encoded selected fields.
+//   - error: An error if v cannot be encoded as a JSON object.
+func selectFields(v any, fields fieldSet) (any, error) {
+	if fields.hasAllDefaults() {
+		return v, nil // Nothing to trim, as the optional fields not selected are empty
+	}
+
+	encoded, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var all map[string]json.RawMessage
+	if err := json.Unmarshal(encoded, &all); err != nil {
+		return nil, err
+	}
+
+	selected := make(map[string]json.RawMessage, len(fields))
+	for field := range fields {
+		if value, ok := all[field]; ok {
+			selected[field] = value
+		}
+	}
+	return selected, nil
+}
@@ -1,167 +1,342 @@
 package handler
 
 import (
-	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
-	"fmt"
+	"github.com/SigurdRiseth/CountryInfoService/cache"
+	"github.com/SigurdRiseth/CountryInfoService/clients"
+	"github.com/SigurdRiseth/CountryInfoService/iso"
 	"github.com/SigurdRiseth/CountryInfoService/utils"
 	"log"
 	"net/http"
 	"strconv"
+	"sync"
+	"time"
 )
 
-// Country represents the structure of the API response
-type Country struct {
-	Name       Name              `json:"name"`
-	Capital    []string          `json:"capital"`
-	Languages  map[string]string `json:"languages"`
-	Borders    []string          `json:"borders"`
-	Flag       string            `json:"flag"`
-	Population int               `json:"population"`
-	Continents []string          `json:"continents"`
-}
-
-// Name represents the naming details of the country
-type Name struct {
-	Common     string                `json:"common"`
-	Official   string                `json:"official"`
-	NativeName map[string]NativeName `json:"nativeName"`
-}
-
-// NativeName represents the native language details
-type NativeName struct {
-	Official string `json:"official"`
-	Common   string `json:"common"`
-}
-
-// HandleInfo processes requests to retrieve country information based on an ISO2 country code.
+// HandleInfo processes requests to retrieve country information based on a country code or name.
 // It fetches details about the country, including cities, with an optional limit on the number of cities returned.
 //
 // Request Parameters:
-//   - "two_letter_country_code" (path parameter): The ISO2 country code (e.g., "US" for the United States).
+//   - "country" (path parameter): The ISO 3166-1 alpha-2, alpha-3 or numeric code, or the common, official
+//     or native name of the country (e.g., "US", "USA", "840" or "United States" for the United States).
 //   - "limit" (query parameter, optional): A string representing the number of cities to retrieve.
+//   - "sort" (query parameter, optional): The alphabetical order of the cities, "asc" (default) or "desc".
+//     Cities are deduplicated and sorted by the alphabet of the main language of the country.
+//   - "state" (query parameter, optional): Only lists the cities of the named state (see resolveState).
+//   - "fields" (query parameter, optional): A comma-separated list of the fields to return (e.g., "name,flag,capital").
+//     Upstream APIs providing none of the fields are not called, e.g. CountriesNow unless "cities" is selected.
+//   - "include" (query parameter, optional): A comma-separated list of optional fields to add to the response:
+//     "officialname", "nativenames", "currencies", "area", "latlng", "timezones", "callingcodes", "tlds",
+//     "subregion" and "unmember". Optional fields are left out by default, and can also be named in "fields".
 //
 // Response:
 //   - Returns a JSON response containing country information or an error message.
 //
 // HTTP Status Codes:
-//   - 200 OK: Successfully retrieved country information.
-//   - 400 Bad Request: Invalid input parameters.
-//   - 500 Internal Server Error: Failed to retrieve country information.
+//   - 200 OK: Successfully retrieved country information. If the cities could not be retrieved,
+//     the country information is still returned with null cities, "partial": true and a warning.
+//   - 400 Bad Request: Unknown country (with suggestions for the countries the client may have meant),
+//     invalid sort order, or unknown field or optional field.
+//   - 404 Not Found: No country exists for the country code, or the state does not exist in the country.
+//   - 502 Bad Gateway / 503 Service Unavailable: The RestCountries API answered unexpectedly or cannot be reached.
+//   - 504 Gateway Timeout: The request deadline passed before the upstream APIs answered.
 //
 // Example Usage:
 //
 //	GET /info/US?limit=10 -> Retrieves information about the United States with a limit of 10 cities.
+//	GET /info/NO?sort=desc -> Retrieves information about Norway, starting the cities from the end of the Norwegian alphabet.
+//	GET /info/NG?state=lagos -> Retrieves information about Nigeria, listing the cities of Lagos State.
+//	GET /info/NO?fields=name,flag,capital -> Retrieves the name, flag and capital of Norway only, without fetching cities.
+//	GET /info/NO?include=currencies,callingcodes -> Retrieves information about Norway, including its currencies and calling codes.
 //
-// Returns an error if fetching country information fails.
+// Returns a utils.APIError if fetching country information fails, leaving the error response to the caller.
 func HandleInfo(w http.ResponseWriter, r *http.Request) error {
+	return handleInfo(w, r, 1)
+}
+
+// HandleInfoV2 processes requests to retrieve country information in version 2 of the API.
+// It takes the same parameters as HandleInfo, but responds with a utils.CountryInfoV2, listing every
+// capital of the country instead of only the first one, and null for countries without a capital.
+//
+// Example Usage:
+//
+//	GET /countryinfo/v2/info/ZA -> Retrieves information about South Africa, with all three of its capitals.
+//
+// Returns a utils.APIError if fetching country information fails, leaving the error response to the caller.
+func HandleInfoV2(w http.ResponseWriter, r *http.Request) error {
+	return handleInfo(w, r, 2)
+}
+
+// handleInfo implements HandleInfo and HandleInfoV2, responding with the country information
+// in the shape of the given version of the API.
+func handleInfo(w http.ResponseWriter, r *http.Request, version int) error {
 	// Set response content type to JSON
 	w.Header().Set("Content-Type", "application/json")
 
 	// Extract query parameters and default values
-	isoCode := r.PathValue("two_letter_country_code")
+	countryParam := r.PathValue("country")
 	cityLimitStr := r.URL.Query().Get("limit")
+	descending, err := parseSortOrder(r.URL.Query().Get("sort"))
+	if err != nil {
+		return err
+	}
+	fields, err := parseFields(r.URL.Query().Get("fields"))
+	if err != nil {
+		return err
+	}
+	fields, err = parseInclude(r.URL.Query().Get("include"), fields)
+	if err != nil {
+		return err
+	}
 
-	// Fetch country info and handle errors
-	info, err := getCountryInfo(isoCode, cityLimitStr)
+	// Resolve the country to its ISO2 code, rejecting unknown countries before calling the upstream APIs
+	country, err := resolveCountry(countryParam)
+	if err != nil {
+		return err
+	}
 
-	// Construct response based on error presence
-	apiResponse := utils.APIResponse{
-		Error:   err != nil,
-		Message: "Country information retrieved successfully",
-		Data:    info,
+	// Fetch country info and handle errors, keeping track of the cached values used
+	ctx, meta := cache.WithMeta(r.Context())
+	timing := newServerTiming()
+	options := infoOptions{cityLimit: cityLimitStr, descending: descending, state: r.URL.Query().Get("state"), fields: fields}
+	info, warnings, err := getCountryInfo(ctx, country, options, timing)
+	w.Header().Set("Server-Timing", timing.String())
+	if deadlineExceeded(ctx) && err != nil {
+		return errRequestTimeout
+	}
+	if err != nil {
+		return err
 	}
 
-	// Set error message if an error occurred
+	// Construct response in the shape of the requested version, leaving out the fields not selected
+	var data any = info.CountryInfo
+	if version >= 2 {
+		data = info
+	}
+	data, err = selectFields(data, fields)
 	if err != nil {
-		apiResponse.Message = err.Error()
-		apiResponse.Data = nil
+		return err
+	}
+	apiResponse := utils.APIResponse{
+		Error:    false,
+		Message:  "Country information retrieved successfully",
+		Data:     data,
+		Stale:    meta.Stale(),
+		Partial:  len(warnings) > 0,
+		Warnings: warnings,
+	}
+
+	if apiResponse.Partial {
+		// Do not let clients hold on to an incomplete response
+		apiResponse.Message = "Country information partially retrieved"
+		w.Header().Set("Cache-Control", "no-store")
+	} else {
+		setCacheHeaders(w, meta)
 	}
 
 	// Marshal and send the JSON response
-	response, _ := json.Marshal(apiResponse)
+	response, err := json.Marshal(apiResponse)
+	if err != nil {
+		return err
+	}
 	w.WriteHeader(http.StatusOK)
-	w.Write(response)
+	_, err = w.Write(response)
 	return err
 }
 
+// infoOptions holds the query parameters of an info request shaping the response.
+type infoOptions struct {
+	cityLimit  string   // The maximum number of cities, see limitCities
+	descending bool     // Whether to sort the cities in descending order
+	state      string   // The state to list the cities of, or empty for all cities
+	fields     fieldSet // The fields to retrieve, or nil for all non-optional fields
+}
+
 // getCountryInfo retrieves country information from an external API based on the provided ISO2 country code.
 // It fetches details such as country name, continents, population, languages, borders, flag, capital, and cities.
 //
 // Parameters:
-//   - isoCode (string): The ISO2 country code (e.g., "US" for the United States).
-//   - cityLimitStr (string): A string representing the maximum number of cities to retrieve (optional).
+//   - ctx (context.Context): The context of the incoming request, passed on to the upstream calls.
+//   - country (iso.Country): The country (e.g., the United States).
+//   - options (infoOptions): The fields to retrieve, and how to select, order and limit the cities.
+//   - timing (*serverTiming): Collects the duration of every upstream call.
 //
 // Returns:
-//   - utils.CountryInfo: A struct containing the retrieved country information.
-//   - error: An error if the request fails or the response cannot be processed.
+//   - utils.CountryInfoV2: A struct containing the retrieved country information, with every capital of the country.
+//     The embedded utils.CountryInfo holds the first capital only, as returned by version 1 of the API.
+//   - []utils.Warning: Warnings about optional data (the cities) that could not be retrieved.
+//   - error: An error if the country details cannot be retrieved.
 //
 // Function Workflow:
-//   - Constructs the API request URL.
-//   - Makes an HTTP GET request to fetch country data.
-//   - Decodes the JSON response into a Country struct.
-//   - Extracts relevant country data into a utils.CountryInfo struct.
-//   - Fetches cities using an additional API call and applies an optional limit.
+//   - Fetches the country data through the injected RestCountries client and the cities through
+//     getCities concurrently, sharing the deadline of the request. Either is skipped if none of
+//     its fields are selected, leaving the fields empty.
+//   - If the country data cannot be fetched, the city request is cancelled, as it would be discarded anyway.
+//   - If the requested state does not exist, an error is returned rather than a partial response.
+//   - Extracts relevant country data into a utils.CountryInfo struct, including the selected optional fields, and applies the optional city limit to the sorted cities.
+//     If the cities could not be fetched, they are left null and a warning is returned instead of an error.
 //
 // Errors:
-//   - Returns an error if the API request fails, the response is invalid, or JSON decoding fails.
+//   - Returns an error if the RestCountries request fails, the response is invalid, or JSON decoding fails.
 //
 // Example Usage:
 //
-//	info, err := getCountryInfo("US", "10")
+//	country, _ := iso.Resolve("US")
+//	info, warnings, err := getCountryInfo(r.Context(), country, infoOptions{cityLimit: "10"}, newServerTiming())
 //	if err != nil {
 //	    log.Fatal(err)
 //	}
 //	fmt.Println(info)
-func getCountryInfo(isoCode, cityLimitStr string) (utils.CountryInfo, error) {
-	// Construct the URL for the external API
-	url := utils.RestCountriesApiUrl + isoCode + utils.RestCountriesFilter
-	log.Printf("Fetching data from API: %s for country code: %s with limit %s", url, isoCode, cityLimitStr)
+func getCountryInfo(ctx context.Context, country iso.Country, options infoOptions, timing *serverTiming) (utils.CountryInfoV2, []utils.Warning, error) {
+	log.Printf("Fetching country info for country code: %s with limit %s", country.Alpha2, options.cityLimit)
 
-	// Make HTTP request to the external API
-	resp, err := http.Get(url)
-	if err != nil {
-		log.Printf("Error contacting API: %v", err)
-		return utils.CountryInfo{}, errors.New("error contacting API")
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg         sync.WaitGroup
+		details    clients.Country
+		countryErr error
+		cities     []string
+		citiesErr  error
+	)
+
+	// Fetch the country details from the RestCountries API
+	if options.fields.needs(utils.SourceRestCountries) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			start := time.Now()
+			details, countryErr = RestCountries.GetCountry(ctx, country.Alpha2)
+			timing.add(utils.SourceRestCountries, time.Since(start))
+			if countryErr != nil {
+				cancel() // The cities are of no use without the country
+			}
+		}()
 	}
-	defer resp.Body.Close()
 
-	// Ensure the response is successful
-	if resp.StatusCode != http.StatusOK {
-		log.Printf("Rest-Countries-API returned status code: %d", resp.StatusCode)
-		return utils.CountryInfo{}, fmt.Errorf("API returned error status code %d", resp.StatusCode)
+	// Fetch the cities from the CountriesNow API
+	fetchCities := options.fields.needs(utils.SourceCountriesNow)
+	if fetchCities {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			start := time.Now()
+			cities, citiesErr = getCities(ctx, country, options.state, options.descending)
+			timing.add(utils.SourceCountriesNow, time.Since(start))
+		}()
 	}
 
-	// Decode the JSON response into the appropriate struct
-	var apiResponse Country
-	if err := json.NewDecoder(resp.Body).Decode(&apiResponse); err != nil {
-		log.Printf("Error decoding JSON: %v", err)
-		return utils.CountryInfo{}, errors.New("error decoding JSON")
+	wg.Wait()
+	if countryErr != nil {
+		return utils.CountryInfoV2{}, nil, countryErr
+	}
+	if errors.Is(citiesErr, clients.ErrStateNotFound) {
+		return utils.CountryInfoV2{}, nil, citiesErr
 	}
 
-	// Extract country data from the response
-	country := apiResponse
-	info := utils.CountryInfo{
-		Name:       country.Name.Common,
-		Continents: country.Continents,
-		Population: country.Population,
-		Languages:  country.Languages,
-		Borders:    country.Borders,
-		Flag:       country.Flag,
-		Capital:    country.Capital[0],
-		Cities:     nil,
+	// Extract country data from the response. Countries without a capital (e.g. Antarctica) get an
+	// empty capital in version 1 of the API, and a null list of capitals in version 2.
+	info := utils.CountryInfoV2{
+		CountryInfo: utils.CountryInfo{
+			Name:       details.Name.Common,
+			Continents: details.Continents,
+			Population: details.Population,
+			Languages:  details.Languages,
+			Borders:    details.Borders,
+			Flag:       details.Flag,
+			Cities:     nil,
+		},
+	}
+	if len(details.Capital) > 0 {
+		info.CountryInfo.Capital = details.Capital[0]
+		info.Capital = details.Capital
+	}
+	addOptionalFields(&info.CountryInfo, details, options.fields)
+	if !fetchCities {
+		return info, nil, nil
 	}
 
-	// Fetch cities based on the country code and limit
-	citiesFromAPI, err := fetchCitiesFromAPI(isoCode)
-	if err != nil {
-		return utils.CountryInfo{}, errors.New("error fetching cities")
+	// Apply the city limit, degrading to a partial response if the cities could not be fetched
+	if citiesErr != nil {
+		log.Printf("Error fetching cities: %v", citiesErr)
+		return info, []utils.Warning{{
+			Source:  utils.SourceCountriesNow,
+			Field:   "cities",
+			Message: "error fetching cities",
+		}}, nil
 	}
-	cities := limitCities(citiesFromAPI.Data, cityLimitStr)
-	info.Cities = cities
+	info.Cities = limitCities(cities, options.cityLimit)
 
-	return info, nil
+	return info, nil, nil
+}
+
+// addOptionalFields copies the selected optional fields (see optionalInfoFields) from the country details to info.
+// Optional fields not selected are left empty, so that they are omitted from the response.
+//
+// Parameters:
+//   - info (*utils.CountryInfo): The country information to add the fields to.
+//   - details (clients.Country): The country details as returned by the RestCountries API.
+//   - fields (fieldSet): The selected fields.
+func addOptionalFields(info *utils.CountryInfo, details clients.Country, fields fieldSet) {
+	if fields.has("officialname") {
+		info.OfficialName = details.Name.Official
+	}
+	if fields.has("nativenames") {
+		info.NativeNames = make(map[string]utils.NativeName, len(details.Name.NativeName))
+		for language, name := range details.Name.NativeName {
+			info.NativeNames[language] = utils.NativeName{Common: name.Common, Official: name.Official}
+		}
+	}
+	if fields.has("currencies") {
+		info.Currencies = make(map[string]utils.Currency, len(details.Currencies))
+		for code, currency := range details.Currencies {
+			info.Currencies[code] = utils.Currency{Name: currency.Name, Symbol: currency.Symbol}
+		}
+	}
+	if fields.has("area") {
+		info.Area = &details.Area
+	}
+	if fields.has("latlng") {
+		info.LatLng = details.Latlng
+	}
+	if fields.has("timezones") {
+		info.Timezones = details.Timezones
+	}
+	if fields.has("callingcodes") {
+		info.CallingCodes = callingCodes(details.Idd)
+	}
+	if fields.has("tlds") {
+		info.TLDs = details.Tld
+	}
+	if fields.has("subregion") {
+		info.Subregion = &details.Subregion
+	}
+	if fields.has("unmember") {
+		info.UNMember = &details.UnMember
+	}
+}
+
+// callingCodes returns the international calling codes of a country: the root of its
+// international direct dialing details followed by each of the suffixes.
+//
+// Example Usage:
+//
+//	codes := callingCodes(clients.Idd{Root: "+4", Suffixes: []string{"7"}}) // ["+47"]
+func callingCodes(idd clients.Idd) []string {
+	if idd.Root == "" {
+		return nil
+	}
+	if len(idd.Suffixes) == 0 {
+		return []string{idd.Root}
+	}
+	codes := make([]string, 0, len(idd.Suffixes))
+	for _, suffix := range idd.Suffixes {
+		codes = append(codes, idd.Root+suffix)
+	}
+	return codes
 }
 
 // limitCities limits the number of cities returned based on a given limit string.
@@ -197,60 +372,3 @@ func limitCities(cities []string, limitString string) []string {
 	}
 	return cities
 }
-
-// fetchCitiesFromAPI fetches a list of cities for a given country ISO2 code from the Countries-Now API.
-//
-// Parameters:
-//   - isoCode (string): The two-letter country code (ISO2).
-//
-// Returns:
-//   - *utils.APIResponseString: A pointer to the API response containing city data if successful, otherwise nil.
-//   - error: An error if the request fails or the response contains an error message.
-//
-// Behavior:
-//   - Constructs a request payload with the given `isoCode`.
-//   - Sends a POST request to the Countries-Now API to retrieve city data.
-//   - Handles potential errors, including JSON encoding/decoding issues and API response failures.
-//   - Checks if the API response contains an error and returns an appropriate error message.
-//
-// Example Usage:
-//
-//	response, err := fetchCitiesFromAPI("NO")
-//	if err != nil {
-//	    log.Println("Error fetching cities:", err)
-//	} else {
-//	    log.Println("Cities:", response.Data)
-//	}
-func fetchCitiesFromAPI(isoCode string) (*utils.APIResponseString, error) {
-	// Construct the URL for the external API
-	url := utils.CountriesNowApiUrl + utils.CountriesNowCityEndpoint
-	log.Println("Fetching city data from API:", url)
-
-	// Construct the request payload
-	requestBody, err := json.Marshal(map[string]string{"iso2": isoCode})
-	if err != nil {
-		return nil, errors.New("failed to encode request payload for city data")
-	}
-
-	// Make HTTP request to the external API
-	resp, err := http.Post(url, "application/json", bytes.NewBuffer(requestBody))
-	if err != nil {
-		return nil, errors.New("failed to reach Countries-Now API for city data")
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("Countries-Now API returned error status code: %d", resp.StatusCode)
-	}
-
-	var apiResponse utils.APIResponseString
-	if err := json.NewDecoder(resp.Body).Decode(&apiResponse); err != nil {
-		return nil, errors.New("failed to decode Countries-Now API response")
-	}
-
-	if apiResponse.Error {
-		return nil, errors.New("Countries-Now API returned an error: " + apiResponse.Message)
-	}
-
-	return &apiResponse, nil
-}
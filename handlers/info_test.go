@@ -0,0 +1,271 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"github.com/SigurdRiseth/CountryInfoService/iso"
+	"github.com/SigurdRiseth/CountryInfoService/utils"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHandleInfo(t *testing.T) {
+	_, countriesNow := useFakeClients(t)
+	countriesNow.Cities["NO"] = []string{"Oslo", "Ålesund", "Bergen", "Oslo", "Zeta"}
+
+	w, err := serve(HandleInfo, "/countryinfo/v1/info/no?limit=3", map[string]string{"country": "no"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, w.Code)
+	}
+
+	var info utils.CountryInfo
+	response := decodeResponse(t, w, &info)
+	if response.Error || response.Partial {
+		t.Errorf("expected a complete response, got %+v", response)
+	}
+	if info.Name != "Norway" || info.Capital != "Oslo" || info.Population != 5379475 {
+		t.Errorf("unexpected country details %+v", info)
+	}
+	if want := []string{"Bergen", "Oslo", "Zeta"}; !slices.Equal(info.Cities, want) {
+		t.Errorf("expected cities %v, got %v", want, info.Cities)
+	}
+}
+
+func TestHandleInfoResolvesNames(t *testing.T) {
+	useFakeClients(t)
+
+	for _, input := range []string{"NO", "nor", "578", "Norway", "Kingdom of Norway", "Norge"} {
+		w, err := serve(HandleInfo, "/countryinfo/v1/info/x?fields=name", map[string]string{"country": input})
+		if err != nil {
+			t.Errorf("%s: %v", input, err)
+			continue
+		}
+		var info map[string]string
+		decodeResponse(t, w, &info)
+		if info["name"] != "Norway" {
+			t.Errorf("%s: expected Norway, got %v", input, info)
+		}
+	}
+}
+
+func TestHandleInfoUnknownCountry(t *testing.T) {
+	useFakeClients(t)
+
+	_, err := serve(HandleInfo, "/countryinfo/v1/info/xx", map[string]string{"country": "xx"})
+	assertErrorKind(t, err, utils.KindInvalidInput)
+
+	var apiErr *utils.APIError
+	if errors.As(err, &apiErr) && len(apiErr.Suggestions) == 0 {
+		t.Error("expected suggestions for an unknown country")
+	}
+}
+
+func TestHandleInfoCitiesUnavailable(t *testing.T) {
+	useFakeClients(t) // No cities are registered for Norway
+
+	w, err := serve(HandleInfo, "/countryinfo/v1/info/no", map[string]string{"country": "no"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var info utils.CountryInfo
+	response := decodeResponse(t, w, &info)
+	if !response.Partial || len(response.Warnings) != 1 || response.Warnings[0].Field != "cities" {
+		t.Errorf("expected a partial response with a cities warning, got %+v", response)
+	}
+	if info.Name != "Norway" || info.Cities != nil {
+		t.Errorf("expected the country details without cities, got %+v", info)
+	}
+	if cacheControl := w.Header().Get("Cache-Control"); cacheControl != "no-store" {
+		t.Errorf("expected a partial response not to be stored, got Cache-Control %q", cacheControl)
+	}
+}
+
+func TestHandleInfoUnknownState(t *testing.T) {
+	restCountries, _ := useFakeClients(t)
+	nigeria := restCountries.Countries["NO"]
+	nigeria.Name.Common = "Nigeria"
+	restCountries.Countries["NG"] = nigeria
+
+	// Atlantis is not a state of Nigeria, while Kano State is but has no registered cities
+	for _, state := range []string{"atlantis", "kano"} {
+		_, err := serve(HandleInfo, "/countryinfo/v1/info/ng?state="+state, map[string]string{"country": "ng"})
+		assertErrorKind(t, err, utils.KindNotFound)
+	}
+}
+
+func TestHandleInfoRestCountriesUnavailable(t *testing.T) {
+	restCountries, _ := useFakeClients(t)
+	restCountries.Err = utils.NewUpstreamUnavailableError(utils.SourceRestCountries, "unavailable", nil)
+
+	_, err := serve(HandleInfo, "/countryinfo/v1/info/no", map[string]string{"country": "no"})
+	assertErrorKind(t, err, utils.KindUpstreamUnavailable)
+}
+
+func TestHandleInfoFieldsSkipUpstreamCalls(t *testing.T) {
+	_, countriesNow := useFakeClients(t)
+	countriesNow.Err = errors.New("CountriesNow must not be called without the cities field")
+
+	w, err := serve(HandleInfo, "/countryinfo/v1/info/no?fields=name,capital", map[string]string{"country": "no"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var info map[string]any
+	response := decodeResponse(t, w, &info)
+	if response.Partial || len(info) != 2 || info["capital"] != "Oslo" {
+		t.Errorf("expected the name and capital only, got %v", info)
+	}
+}
+
+func TestHandleInfoInclude(t *testing.T) {
+	useFakeClients(t)
+
+	w, err := serve(HandleInfo, "/countryinfo/v1/info/no?fields=name&include=currencies,callingcodes,unmember",
+		map[string]string{"country": "no"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var info utils.CountryInfo
+	decodeResponse(t, w, &info)
+	if info.Currencies["NOK"].Symbol != "kr" || !slices.Equal(info.CallingCodes, []string{"+47"}) ||
+		info.UNMember == nil || !*info.UNMember || info.Area != nil {
+		t.Errorf("expected the included fields only, got %+v", info)
+	}
+
+	_, err = serve(HandleInfo, "/countryinfo/v1/info/no?include=flag", map[string]string{"country": "no"})
+	assertErrorKind(t, err, utils.KindInvalidInput)
+}
+
+func TestHandleInfoV2Capitals(t *testing.T) {
+	restCountries, _ := useFakeClients(t)
+	southAfrica := restCountries.Countries["NO"]
+	southAfrica.Name.Common, southAfrica.Capital = "South Africa", []string{"Pretoria", "Bloemfontein", "Cape Town"}
+	restCountries.Countries["ZA"] = southAfrica
+
+	w, err := serve(HandleInfoV2, "/countryinfo/v2/info/za?fields=name,capital", map[string]string{"country": "za"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var info utils.CountryInfoV2
+	decodeResponse(t, w, &info)
+	if !slices.Equal(info.Capital, southAfrica.Capital) {
+		t.Errorf("expected every capital in version 2, got %v", info.Capital)
+	}
+
+	w, err = serve(HandleInfo, "/countryinfo/v1/info/za?fields=name,capital", map[string]string{"country": "za"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var infoV1 utils.CountryInfo
+	decodeResponse(t, w, &infoV1)
+	if infoV1.Capital != "Pretoria" {
+		t.Errorf("expected the first capital only in version 1, got %q", infoV1.Capital)
+	}
+}
+
+func TestHandleInfoWithoutCapital(t *testing.T) {
+	restCountries, _ := useFakeClients(t)
+	antarctica := restCountries.Countries["NO"]
+	antarctica.Name.Common, antarctica.Capital = "Antarctica", nil
+	restCountries.Countries["AQ"] = antarctica
+
+	tests := []struct {
+		handler func(http.ResponseWriter, *http.Request) error
+		target  string
+		want    any
+	}{
+		{HandleInfoV2, "/countryinfo/v2/info/aq?fields=name,capital", nil},
+		{HandleInfo, "/countryinfo/v1/info/aq?fields=name,capital", ""},
+		{HandleInfo, "/countryinfo/v1/info/aq", ""},
+	}
+	for _, tt := range tests {
+		w, err := serve(tt.handler, tt.target, map[string]string{"country": "aq"})
+		if err != nil {
+			t.Fatalf("%s: %v", tt.target, err)
+		}
+		var info map[string]any
+		decodeResponse(t, w, &info)
+		if capital, ok := info["capital"]; !ok || capital != tt.want {
+			t.Errorf("%s: expected capital %#v, got %v", tt.target, tt.want, info)
+		}
+	}
+}
+
+func TestGetCountryInfoFetchesConcurrently(t *testing.T) {
+	restCountries, countriesNow := useFakeClients(t)
+	countriesNow.Cities["NO"] = []string{"Oslo", "Bergen"}
+	restCountries.Delay, countriesNow.Delay = 150*time.Millisecond, 150*time.Millisecond
+
+	country, _ := iso.Resolve("NO")
+	start := time.Now()
+	info, warnings, err := getCountryInfo(context.Background(), country, infoOptions{}, newServerTiming())
+	elapsed := time.Since(start)
+	if err != nil || len(warnings) > 0 {
+		t.Fatalf("expected a complete response, got %v and warnings %+v", err, warnings)
+	}
+	if info.Name != "Norway" || len(info.Cities) != 2 {
+		t.Errorf("unexpected country information %+v", info)
+	}
+	if elapsed >= 250*time.Millisecond {
+		t.Errorf("expected both calls to run in parallel in about 150ms, took %s", elapsed)
+	}
+}
+
+func TestGetCountryInfoCancelsCities(t *testing.T) {
+	restCountries, countriesNow := useFakeClients(t)
+	countriesNow.Cities["NO"] = []string{"Oslo", "Bergen"}
+	restCountries.Err = utils.NewUpstreamUnavailableError(utils.SourceRestCountries, "unavailable", nil)
+	countriesNow.Delay = time.Second
+
+	country, _ := iso.Resolve("NO")
+	start := time.Now()
+	_, _, err := getCountryInfo(context.Background(), country, infoOptions{}, newServerTiming())
+	assertErrorKind(t, err, utils.KindUpstreamUnavailable)
+	if elapsed := time.Since(start); elapsed >= 500*time.Millisecond {
+		t.Errorf("expected the cities to be cancelled once RestCountries failed, took %s", elapsed)
+	}
+}
+
+func TestHandleInfoServerTiming(t *testing.T) {
+	_, countriesNow := useFakeClients(t)
+	countriesNow.Cities["NO"] = []string{"Oslo", "Bergen"}
+	countriesNow.Delay = 20 * time.Millisecond
+
+	w, err := serve(HandleInfo, "/countryinfo/v1/info/no", map[string]string{"country": "no"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	durations := make(map[string]float64)
+	for _, metric := range strings.Split(w.Header().Get("Server-Timing"), ", ") {
+		name, value, ok := strings.Cut(metric, ";dur=")
+		duration, err := strconv.ParseFloat(value, 64)
+		if !ok || err != nil {
+			t.Fatalf("malformed Server-Timing metric %q", metric)
+		}
+		durations[name] = duration
+	}
+	if len(durations) != 2 || durations[utils.SourceCountriesNow] < 20 {
+		t.Errorf("expected the duration of both upstream calls in milliseconds, got %v", durations)
+	}
+	if _, ok := durations[utils.SourceRestCountries]; !ok {
+		t.Errorf("expected the duration of the RestCountries call, got %v", durations)
+	}
+
+	w, err = serve(HandleInfo, "/countryinfo/v1/info/no?fields=name", map[string]string{"country": "no"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if timing := w.Header().Get("Server-Timing"); strings.Contains(timing, utils.SourceCountriesNow) {
+		t.Errorf("expected no timing for the skipped CountriesNow call, got %q", timing)
+	}
+}
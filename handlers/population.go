@@ -1,286 +1,236 @@
 package handler
 
 import (
-	"bytes"
 	"encoding/json"
-	"errors"
 	"fmt"
+	"github.com/SigurdRiseth/CountryInfoService/cache"
 	"github.com/SigurdRiseth/CountryInfoService/utils"
 	"log"
+	"math"
 	"net/http"
-	"strconv"
+	"slices"
 	"strings"
-	"time"
 )
 
-// IsoCodeResponse represents the response structure for looking up the ISO3 code
-// from an ISO2 country code. This is used when making a request to the RestCountries API.
-type IsoCodeResponse struct {
-	Cca3 string `json:"cca3"`
-}
-
-// populationAPIResponse represents the structure of the population data response
-// from the external API (e.g., CountriesNow API). It contains the country information
-// and the population data for various years.
-type populationAPIResponse struct { // TODO: USE struct in utils.structs.go instead?
-	Error bool   `json:"error"`
-	Msg   string `json:"msg"`
-	Data  struct {
-		Country          string            `json:"country"`
-		Code             string            `json:"code"`
-		Iso3             string            `json:"iso3"`
-		PopulationCounts []utils.YearValue `json:"populationCounts"`
-	} `json:"data"`
-}
+// Methods filling in the years missing from a population history
+const (
+	fillNone     = "none"
+	fillLinear   = "linear"
+	fillPrevious = "previous"
+)
 
-// HandlePopulation processes the population data for a given country based on its ISO2 country code.
+// HandlePopulation processes the population data for a given country based on its country code or name.
 //
 // This function handles the full flow of fetching population data for a country:
-//   - Converts the provided ISO2 country code to an ISO3 code using the `getIso3` function.
-//   - Sends a POST request to an external API (CountriesNow API) with the ISO3 code to retrieve population data.
-//   - Filters the population data based on the provided year range (if any).
-//   - Calculates the mean population for the filtered data.
+//   - Resolves the provided country code or name to the ISO3 code of the country using the embedded ISO 3166-1 table.
+//   - Retrieves the population data for the ISO3 code using the injected CountriesNow client.
+//   - Fills in the years missing from the population data as requested (see fillMissingYears).
+//   - Filters the population data based on the provided year ranges (if any).
+//   - Calculates the mean population for the filtered data, and the requested statistics (see computePopulationStats).
 //   - Constructs a JSON response with the filtered data and mean population.
 //
 // If any step fails, an appropriate error response is returned to the client.
 //
 // Parameters:
 //   - w: The `http.ResponseWriter` to send the response to the client.
-//   - r: The `http.Request` that contains the ISO2 country code in the URL and the optional 'limit' query parameter for year range.
+//   - r: The `http.Request` that contains the country code or name in the URL and the optional 'limit' query parameter
+//     for the years to return, as a comma-separated list of year ranges (see parseYearLimit), e.g. "2000-2010,last:5".
+//     The optional 'stats' query parameter requests statistics of the filtered data, as a comma-separated list of
+//     "median", "min", "max", "stddev", "changes", "cagr" and "doublingtime", or "all" for every statistic.
+//     Without it, the response holds the mean and values only.
+//     The optional 'fill' query parameter fills in the years missing from the data: "none" (default),
+//     "linear" or "previous". Filled in values are flagged as estimated, and count towards the mean and statistics.
 //
 // Responses:
 //   - If successful, a JSON response with the population data and the mean population is returned.
 //   - If there is an error, an error response is returned with a relevant message and status code.
 //
 // Error Handling:
-//   - BadRequest (400): If the ISO2 code cannot be converted to ISO3.
-//   - InternalServerError (500): If there is an issue marshaling the JSON or contacting the external API.
-//   - ServiceUnavailable (503): If the external API cannot be reached.
-//   - UnprocessableEntity (422): If the provided year range is invalid.
-//
-// This function ensures that population data is accurately retrieved and returned to the client, with proper error handling at each step.
-func HandlePopulation(w http.ResponseWriter, r *http.Request) {
+//   - BadRequest (400): If the country is unknown (with suggestions), the provided year range or fill method is invalid,
+//     or a statistic is unknown.
+//   - NotFound (404): If no population data exists for the country.
+//   - BadGateway (502) / ServiceUnavailable (503): If an external API answers unexpectedly or cannot be reached.
+//   - GatewayTimeout (504): If the request deadline passed before the upstream APIs answered.
+//
+// Errors are returned as a utils.APIError, leaving the error response to the caller.
+func HandlePopulation(w http.ResponseWriter, r *http.Request) error {
 	w.Header().Set("Content-Type", "application/json")
 
-	url := utils.CountriesNowApiUrl + utils.CountriesNowPopulationEndpoint
-	isoCode := r.PathValue("two_letter_country_code")
+	countryParam := r.PathValue("country")
 	limit := r.URL.Query().Get("limit")
-
-	log.Printf("Fetching data from API: %s for country code: %s with limit %s", url, isoCode, limit)
-
-	// Convert ISO2 to ISO3
-	iso3, err := getIso3(isoCode)
+	stats, err := parseStats(r.URL.Query().Get("stats"))
 	if err != nil {
-		handleError(w, http.StatusBadRequest, "Error fetching ISO3 code from ISO2: "+isoCode)
-		return
+		return err
 	}
-
-	// Create JSON payload
-	requestBody, err := json.Marshal(map[string]string{"iso3": iso3})
+	fill, err := parseFill(r.URL.Query().Get("fill"))
 	if err != nil {
-		handleError(w, http.StatusInternalServerError, "Error marshalling JSON")
-		return
+		return err
 	}
-
-	// Make HTTP request to the external API
-	resp, err := http.Post(url, "application/json", bytes.NewBuffer(requestBody))
+	ranges, err := parseYearLimit(limit)
 	if err != nil {
-		handleError(w, http.StatusServiceUnavailable, "Error contacting external API")
-		return
+		return err
 	}
-	defer resp.Body.Close()
 
-	// Decode the JSON response
-	var apiResponse populationAPIResponse
-	err = json.NewDecoder(resp.Body).Decode(&apiResponse)
+	log.Printf("Fetching population data for country: %s with limit %s", countryParam, limit)
+
+	// Resolve the country to its ISO3 code, rejecting unknown countries before calling the upstream APIs
+	country, err := resolveCountry(countryParam)
 	if err != nil {
-		handleError(w, http.StatusInternalServerError, "Error decoding JSON response")
-		return
+		return err
 	}
 
-	// Filter population data
-	filteredValues, err := filterByYearLimit(apiResponse.Data.PopulationCounts, limit)
+	// Keep track of the cached values used to build the response
+	ctx, meta := cache.WithMeta(r.Context())
+
+	// Fetch the population history from the CountriesNow API
+	populationData, err := CountriesNow.GetPopulation(ctx, country.Alpha3)
+	if deadlineExceeded(ctx) && err != nil {
+		return errRequestTimeout
+	}
 	if err != nil {
-		handleError(w, http.StatusUnprocessableEntity, "Invalid year range format. Expected 'startYear-endYear'.")
-		return
+		return err
 	}
 
-	// Calculate the mean population
-	sum := 0
-	for _, v := range filteredValues {
-		sum += v.Value
+	// Fill in the missing years before filtering, so that years at the edges of the range can be estimated
+	ranges = resolveYearRanges(ranges, populationData.PopulationCounts)
+	filledValues := fillMissingYears(populationData.PopulationCounts, fill, ranges)
+
+	// Filter population data
+	filteredValues := filterByYearRanges(filledValues, ranges)
+
+	// Calculate the mean population, and the requested statistics, leaving out the others
+	selectedStats, err := selectStats(filteredValues, stats)
+	if err != nil {
+		return err
 	}
-	mean := 0
-	if len(filteredValues) > 0 {
-		mean = sum / len(filteredValues)
+	populationInfo := utils.PopulationInfo{
+		Values: filteredValues,
+		Mean:   meanPopulation(filteredValues),
+		Stats:  selectedStats,
 	}
 
 	// Construct response
 	response := utils.APIResponse{
 		Error:   false,
 		Message: "Population data retrieved successfully",
-		Data:    utils.PopulationInfo{Values: filteredValues, Mean: mean},
+		Data:    populationInfo,
+		Stale:   meta.Stale(),
 	}
 
 	// Send response
-	if err := json.NewEncoder(w).Encode(response); err != nil {
-		log.Printf("Error encoding JSON response: %v", err)
-	}
+	setCacheHeaders(w, meta)
+	return json.NewEncoder(w).Encode(response)
 }
 
-// handleError sends an error response to the client with the provided status code and message.
-//
-// This function logs the error message and then formats a JSON response with the provided status code,
-// a message indicating the error, and a `nil` data field. The response is sent to the client via the
-// `http.ResponseWriter`.
-//
-// Parameters:
-//   - w: The `http.ResponseWriter` to send the response to the client.
-//   - statusCode: The HTTP status code to be sent in the response (e.g., 400 for bad request, 500 for internal server error).
-//   - message: A string containing the error message that will be included in the response body.
-//
-// Example usage:
-//
-//	handleError(w, http.StatusBadRequest, "Invalid country code provided")
-//
-// This function is useful for centralizing error handling and ensuring consistent error responses throughout the API.
-func handleError(w http.ResponseWriter, statusCode int, message string) {
-	log.Printf("Error: %s", message)
-	w.WriteHeader(statusCode)
-	json.NewEncoder(w).Encode(utils.APIResponse{
-		Error:   true,
-		Message: message,
-		Data:    nil,
-	})
+// meanPopulation returns the mean of the population values, rounded down, or 0 if there are none.
+func meanPopulation(values []utils.YearValue) int {
+	if len(values) == 0 {
+		return 0
+	}
+	sum := 0
+	for _, v := range values {
+		sum += v.Value
+	}
+	return sum / len(values)
 }
 
-// getIso3 retrieves the ISO3 code corresponding to a given ISO2 code (two-letter country code).
-//
-// It makes an HTTP request to the Rest-Countries API to fetch the ISO3 code. The function
-// includes error handling for failed HTTP requests, non-200 responses, and issues decoding the API response.
-//
-// The function has a timeout of 5 seconds to avoid hanging requests in case the external API is unresponsive.
+// parseFill parses the method filling in the years missing from a population history, defaulting to none if not provided.
 //
 // Parameters:
-//   - isoCode: A string representing the two-letter ISO2 country code for which the ISO3 code is requested.
+//   - fill (string): The fill method, "none", "linear" or "previous".
 //
 // Returns:
-//   - A string representing the ISO3 code if the lookup is successful.
-//   - An error if there is a problem making the request, receiving the response, or decoding the result.
-//
-// Example usage:
-//
-//	iso3Code, err := getIso3("NO") // Retrieves ISO3 code for Norway (NO)
-//	if err != nil {
-//	    log.Println("Error:", err)
-//	} else {
-//	    log.Println("ISO3 Code:", iso3Code)
-//	}
-//
-// Error cases:
-//   - If the request to the Rest-Countries API fails, an error is returned with a message indicating the issue.
-//   - If the response status code is not 200 OK, an error is returned with the unexpected status code.
-//   - If the response body cannot be decoded or contains an invalid ISO3 code, an error is returned.
-func getIso3(isoCode string) (string, error) {
-	client := &http.Client{Timeout: 5 * time.Second} // Timeout to prevent hanging requests
-
-	url := utils.RestCountriesApiUrl + isoCode + utils.RestCountriesIso3Filter
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		return "", fmt.Errorf("failed to create request for ISO3 lookup (%s): %v", isoCode, err)
-	}
-
-	resp, err := client.Do(req)
-	if err != nil {
-		log.Printf("Error contacting Rest-Countries API for %s: %v", isoCode, err)
-		return "", errors.New("failed to reach Rest-Countries API")
-	}
-	defer resp.Body.Close()
-
-	// Check if the response status is 200 OK
-	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("unexpected status code (%d) from Rest-Countries API for %s", resp.StatusCode, isoCode)
-	}
-
-	// Decode JSON response
-	var result IsoCodeResponse
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return "", fmt.Errorf("failed to decode JSON response for %s: %v", isoCode, err)
-	}
-
-	// Ensure the API response contains a valid ISO3 code
-	if result.Cca3 == "" {
-		return "", fmt.Errorf("empty ISO3 code received for %s", isoCode)
+//   - string: The fill method.
+//   - error: An invalid input utils.APIError if the fill method is not recognized.
+func parseFill(fill string) (string, error) {
+	switch fill = strings.ToLower(fill); fill {
+	case "":
+		return fillNone, nil
+	case fillNone, fillLinear, fillPrevious:
+		return fill, nil
+	default:
+		return "", utils.NewInvalidInputError(
+			fmt.Sprintf("invalid fill '%s', expected '%s', '%s' or '%s'", fill, fillNone, fillLinear, fillPrevious), nil)
 	}
-
-	return result.Cca3, nil
 }
 
-// filterByYearLimit filters the given slice of YearValue structs based on a specified year range.
-//
-// If no limit is provided (empty string), the original slice of values is returned without any filtering.
-//
-// The `limit` parameter should be a string in the format 'start-end', where:
-// - 'start' is the starting year (inclusive)
-// - 'end' is the ending year (inclusive)
-//
-// If the `limit` format is invalid or the years are not integers, an error will be returned.
-// Additionally, if the start year is greater than the end year, an error will be returned.
-//
-// Example usage:
-//
-//	values := []YearValue{
-//	    {Year: 2000, Value: 100},
-//	    {Year: 2005, Value: 150},
-//	    {Year: 2010, Value: 200},
-//	}
-//	filteredValues, err := filterByYearLimit(values, "2000-2005")
-//	// filteredValues will contain the YearValue structs for the years 2000 and 2005
+// fillMissingYears fills in the years missing from a population history: the years between the first and the last
+// year of data, and the years of the requested ranges before the first or after the last year of data.
+// Years beyond the data are only filled in for range bounds given explicitly (e.g. the start of "1950-1970"
+// or "last:80", but not the open start of "-1970"), up to utils.MaxFillYears years away from the data.
 //
 // Parameters:
-//   - values: a slice of YearValue structs to be filtered.
-//   - limit: a string in the format 'start-end' specifying the year range to filter by.
+//   - values ([]utils.YearValue): The population history, in any order.
+//   - fill (string): The fill method: fillNone returns the values as they are, fillLinear interpolates linearly
+//     between the surrounding years and extends the trend of the nearest two years beyond the data (never below zero),
+//     and fillPrevious repeats the value of the previous year, or of the first year for years before the data.
+//   - ranges ([]yearRange): The ranges selected by the limit parameter, resolved against the population history
+//     (see resolveYearRanges); nil fills in no years beyond the data.
 //
 // Returns:
-//   - A slice of YearValue structs filtered by the specified year range.
-//   - An error if the limit format is invalid or if other validation issues occur.
-func filterByYearLimit(values []utils.YearValue, limit string) ([]utils.YearValue, error) {
-	// If no limit is provided, return the original values
-	if limit == "" {
-		return values, nil
+//   - []utils.YearValue: The population history with a value for every year, sorted by year,
+//     filled in values being flagged as estimated.
+//
+// Example Usage:
+//
+//	values := []utils.YearValue{{Year: 2000, Value: 100}, {Year: 2003, Value: 160}}
+//	filled := fillMissingYears(values, fillLinear, nil)
+//	// filled holds 2000: 100, 2001: 120 (estimated), 2002: 140 (estimated) and 2003: 160
+//	ranges, _ := parseYearLimit("2003-2004")
+//	filled = fillMissingYears(values, fillLinear, resolveYearRanges(ranges, values))
+//	// filled also holds 2004: 180 (estimated)
+func fillMissingYears(values []utils.YearValue, fill string, ranges []yearRange) []utils.YearValue {
+	if fill == fillNone || len(values) == 0 {
+		return values
+	}
+	values = slices.Clone(values)
+	slices.SortFunc(values, func(a, b utils.YearValue) int { return a.Year - b.Year })
+	first, last := values[0], values[len(values)-1]
+
+	// estimate returns the estimate for the year from the years of data a and b, a being the nearest year for previous
+	estimate := func(year int, a, b utils.YearValue) utils.YearValue {
+		value := a.Value
+		if fill == fillLinear && a.Year != b.Year {
+			progress := float64(year-a.Year) / float64(b.Year-a.Year)
+			value = max(a.Value+int(math.Round(progress*float64(b.Value-a.Value))), 0)
+		}
+		return utils.YearValue{Year: year, Value: value, Estimated: true}
 	}
-
-	// Split the limit into start and end year
-	years := strings.Split(limit, "-")
-	if len(years) != 2 {
-		return nil, fmt.Errorf("invalid limit format, expected 'start-end' but got '%s'", limit)
+	requested := func(year int, bound func(yearRange) bool) bool {
+		for _, r := range ranges {
+			if bound(r) && year >= r.start && year <= r.end {
+				return true
+			}
+		}
+		return false
 	}
 
-	// Convert start year
-	startYear, err := strconv.Atoi(years[0])
-	if err != nil {
-		return nil, fmt.Errorf("invalid start year '%s', expected an integer", years[0])
-	}
+	filled := make([]utils.YearValue, 0, last.Year-first.Year+1)
 
-	// Convert end year
-	endYear, err := strconv.Atoi(years[1])
-	if err != nil {
-		return nil, fmt.Errorf("invalid end year '%s', expected an integer", years[1])
+	// Years before the data, extending the trend of the first two years
+	hasStart := func(r yearRange) bool { return r.start != math.MinInt }
+	for year := first.Year - utils.MaxFillYears; year < first.Year; year++ {
+		if requested(year, hasStart) {
+			filled = append(filled, estimate(year, first, values[min(1, len(values)-1)]))
+		}
 	}
 
-	// Validate the year range
-	if startYear > endYear {
-		return nil, fmt.Errorf("invalid limit format, start year %d cannot be greater than end year %d", startYear, endYear)
+	// Years between the years of data
+	filled = append(filled, first)
+	for i := 1; i < len(values); i++ {
+		previous, next := values[i-1], values[i]
+		for year := previous.Year + 1; year < next.Year; year++ {
+			filled = append(filled, estimate(year, previous, next))
+		}
+		filled = append(filled, next)
 	}
 
-	// Filter the data based on the year range
-	var filtered []utils.YearValue
-	for _, v := range values {
-		if v.Year >= startYear && v.Year <= endYear {
-			filtered = append(filtered, v)
+	// Years after the data, extending the trend of the last two years
+	hasEnd := func(r yearRange) bool { return r.end != math.MaxInt }
+	for year := last.Year + 1; year <= last.Year+utils.MaxFillYears; year++ {
+		if requested(year, hasEnd) {
+			filled = append(filled, estimate(year, last, values[max(len(values)-2, 0)]))
 		}
 	}
-
-	// Return the filtered data
-	return filtered, nil
+	return filled
 }
@@ -0,0 +1,157 @@
+package handler
+
+import (
+	"github.com/SigurdRiseth/CountryInfoService/utils"
+	"net/http"
+	"slices"
+	"testing"
+)
+
+func TestHandlePopulation(t *testing.T) {
+	useFakeClients(t)
+
+	w, err := serve(HandlePopulation, "/countryinfo/v1/population/ng?limit=2002-2004", map[string]string{"country": "ng"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, w.Code)
+	}
+
+	var population utils.PopulationInfo
+	decodeResponse(t, w, &population)
+	if len(population.Values) != 3 || population.Values[0].Year != 2002 || population.Values[2].Year != 2004 {
+		t.Fatalf("expected the years 2002 to 2004, got %+v", population.Values)
+	}
+	if want := (128596076 + 131900631 + 135320422) / 3; population.Mean != want {
+		t.Errorf("expected mean %d, got %d", want, population.Mean)
+	}
+	if population.Stats != nil {
+		t.Errorf("expected no statistics unless requested, got %+v", population.Stats)
+	}
+}
+
+func TestHandlePopulationErrors(t *testing.T) {
+	_, countriesNow := useFakeClients(t)
+
+	tests := []struct {
+		name    string
+		country string
+		target  string
+		kind    utils.ErrorKind
+	}{
+		{"unknown country", "xx", "/countryinfo/v1/population/xx", utils.KindInvalidInput},
+		{"invalid limit", "ng", "/countryinfo/v1/population/ng?limit=2004-2002", utils.KindInvalidInput},
+		{"no population data", "se", "/countryinfo/v1/population/se", utils.KindNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := serve(HandlePopulation, tt.target, map[string]string{"country": tt.country})
+			assertErrorKind(t, err, tt.kind)
+		})
+	}
+
+	countriesNow.Err = utils.NewUpstreamUnavailableError(utils.SourceCountriesNow, "unavailable", nil)
+	_, err := serve(HandlePopulation, "/countryinfo/v1/population/ng", map[string]string{"country": "ng"})
+	assertErrorKind(t, err, utils.KindUpstreamUnavailable)
+}
+
+func TestFillMissingYears(t *testing.T) {
+	values := []utils.YearValue{{Year: 2003, Value: 160}, {Year: 2000, Value: 100}, {Year: 2004, Value: 170}}
+	ranges, err := parseYearLimit("1998-2001,2003-2006")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		fill   string
+		ranges []yearRange
+		want   []int // The value of every year from the first year returned
+		first  int
+	}{
+		{fillLinear, nil, []int{100, 120, 140, 160, 170}, 2000},
+		{fillPrevious, nil, []int{100, 100, 100, 160, 170}, 2000},
+		{fillLinear, ranges, []int{60, 80, 100, 120, 140, 160, 170, 180, 190}, 1998},
+		{fillPrevious, ranges, []int{100, 100, 100, 100, 100, 160, 170, 170, 170}, 1998},
+	}
+	for _, tt := range tests {
+		filled := fillMissingYears(values, tt.fill, tt.ranges)
+		if len(filled) != len(tt.want) {
+			t.Fatalf("%s: expected %d years, got %+v", tt.fill, len(tt.want), filled)
+		}
+		for i, v := range filled {
+			year := tt.first + i
+			estimated := year != 2000 && year != 2003 && year != 2004
+			if v.Year != year || v.Value != tt.want[i] || v.Estimated != estimated {
+				t.Errorf("%s: expected %d: %d (estimated %t), got %+v", tt.fill, year, tt.want[i], estimated, v)
+			}
+		}
+	}
+}
+
+func TestFillMissingYearsBeyondData(t *testing.T) {
+	values := []utils.YearValue{{Year: 2000, Value: 30}, {Year: 2001, Value: 10}}
+
+	// Open bounds fill in no years beyond the data, and explicit bounds at most utils.MaxFillYears years away
+	tests := []struct {
+		limit       string
+		first, last int
+	}{
+		{"-2010", 2000, 2010},
+		{"1900-", 2000 - utils.MaxFillYears, 2001},
+		{"2000-2100", 2000, 2001 + utils.MaxFillYears},
+		{"-1990,2050-", 2000, 2001},
+	}
+	for _, tt := range tests {
+		ranges, err := parseYearLimit(tt.limit)
+		if err != nil {
+			t.Fatal(err)
+		}
+		filled := fillMissingYears(values, fillLinear, ranges)
+		if filled[0].Year != tt.first || filled[len(filled)-1].Year != tt.last || len(filled) != tt.last-tt.first+1 {
+			t.Errorf("%s: expected the years %d to %d, got %+v", tt.limit, tt.first, tt.last, filled)
+		}
+	}
+
+	// A declining trend is not extended below zero
+	ranges, _ := parseYearLimit("2003")
+	if filled := fillMissingYears(values, fillLinear, ranges); filled[len(filled)-1].Value != 0 {
+		t.Errorf("expected the extended trend to stop at zero, got %+v", filled)
+	}
+}
+
+func TestHandlePopulationFillsRangeEdges(t *testing.T) {
+	useFakeClients(t)
+
+	w, err := serve(HandlePopulation, "/countryinfo/v1/population/ng?limit=2017-2020&fill=previous", map[string]string{"country": "ng"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var population utils.PopulationInfo
+	decodeResponse(t, w, &population)
+	if len(population.Values) != 4 || population.Values[3].Year != 2020 || !population.Values[3].Estimated ||
+		population.Values[3].Value != 195874740 {
+		t.Errorf("expected the years after 2018 to repeat its value, got %+v", population.Values)
+	}
+}
+
+func TestHandlePopulationRelativeRangeWithFill(t *testing.T) {
+	useFakeClients(t)
+
+	// The last 3 years are relative to the last year of data (2018), not to the estimated 2020
+	w, err := serve(HandlePopulation, "/countryinfo/v1/population/ng?limit=last:3,2020&fill=linear", map[string]string{"country": "ng"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var population utils.PopulationInfo
+	decodeResponse(t, w, &population)
+	var years []int
+	for _, v := range population.Values {
+		years = append(years, v.Year)
+	}
+	if !slices.Equal(years, []int{2016, 2017, 2018, 2020}) || !population.Values[3].Estimated {
+		t.Errorf("expected the years 2016 to 2018 and the estimated 2020, got %+v", population.Values)
+	}
+}
@@ -0,0 +1,53 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/SigurdRiseth/CountryInfoService/utils"
+	"net/http"
+)
+
+// HandleProblem serves the documentation of a problem type URI, so that the "type" of an
+// RFC 7807 problem details document resolves to a description of the problem.
+//
+// Request Parameters:
+//   - "code" (path parameter): The machine-readable error code (e.g., "upstream_unavailable"), see utils.ErrorKind.Code.
+//
+// Response:
+//   - Returns a JSON response containing the problem type URI, the status code and title of responses
+//     reporting the problem, and a description of the problem.
+//
+// HTTP Status Codes:
+//   - 200 OK: The problem type is documented.
+//   - 404 Not Found: No problem type exists for the code.
+//
+// Example Usage:
+//
+//	GET /countryinfo/v1/problems/upstream_unavailable -> Documentation of the 503 Service Unavailable problem.
+func HandleProblem(w http.ResponseWriter, r *http.Request) error {
+	w.Header().Set("Content-Type", "application/json")
+
+	code := r.PathValue("code")
+	kind, ok := utils.ParseProblemCode(code)
+	if !ok {
+		return utils.NewNotFoundError(fmt.Sprintf("problem type %s not found", code), nil)
+	}
+
+	status := kind.StatusCode()
+	problem := utils.ProblemType{
+		Type:        kind.ProblemType(),
+		Title:       http.StatusText(status),
+		Status:      status,
+		Code:        kind.Code(),
+		Description: kind.Description(),
+	}
+
+	// The documentation only changes when the service is redeployed
+	w.Header().Set("Cache-Control", "public, max-age=86400")
+	w.WriteHeader(http.StatusOK)
+	return json.NewEncoder(w).Encode(utils.APIResponse{
+		Error:   false,
+		Message: "Problem type retrieved successfully",
+		Data:    problem,
+	})
+}
@@ -0,0 +1,158 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/SigurdRiseth/CountryInfoService/cache"
+	"github.com/SigurdRiseth/CountryInfoService/projection"
+	"github.com/SigurdRiseth/CountryInfoService/utils"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// HandleProjection processes requests to project the population of a country into the future.
+//
+// Request Parameters:
+//   - "country" (path parameter): The code or name of the country (see resolveCountry).
+//   - "until" (query parameter, optional): The last year to project, after the last year of data and at most
+//     utils.MaxProjectionYears later. Defaults to utils.DefaultProjectionYears after the last year of data.
+//   - "method" (query parameter, optional): The growth model fitted to the population history,
+//     "linear" (default), "exponential" or "logistic" (see the projection package).
+//
+// Response:
+//   - Returns a JSON response containing a utils.PopulationProjection: the projected population of every year
+//     from the year after the last year of data until the requested year, with the bounds of its confidence band,
+//     and the quality of the fit of the model to the whole population history.
+//
+// HTTP Status Codes:
+//   - 200 OK: Successfully projected the population.
+//   - 400 Bad Request: Unknown country, or invalid year or method.
+//   - 404 Not Found: Not enough population data exists for the country to fit a model.
+//   - 422 Unprocessable Entity: The method cannot be fitted to the data (e.g. an exponential model for a zero population),
+//     or the fitted model does not yield finite values.
+//   - 502 Bad Gateway / 503 Service Unavailable: The CountriesNow API answered unexpectedly or cannot be reached.
+//   - 504 Gateway Timeout: The request deadline passed before the CountriesNow API answered.
+//
+// Example Usage:
+//
+//	GET /countryinfo/v1/population/NO/projection?until=2040&method=exponential -> Projects the population of Norway until 2040.
+//
+// Returns a utils.APIError if the projection fails, leaving the error response to the caller.
+func HandleProjection(w http.ResponseWriter, r *http.Request) error {
+	w.Header().Set("Content-Type", "application/json")
+
+	// Extract and validate the query parameters
+	query := r.URL.Query()
+	methodParam := strings.ToLower(query.Get("method"))
+	if methodParam == "" {
+		methodParam = string(projection.Linear)
+	}
+	method, ok := projection.ParseMethod(methodParam)
+	if !ok {
+		return utils.NewInvalidInputError(fmt.Sprintf("invalid method '%s', expected one of %v", query.Get("method"), projection.Methods), nil)
+	}
+	untilParam := query.Get("until")
+	until, err := strconv.Atoi(untilParam)
+	if untilParam != "" && err != nil {
+		return utils.NewInvalidInputError(fmt.Sprintf("invalid year '%s', expected an integer", untilParam), err)
+	}
+
+	country, err := resolveCountry(r.PathValue("country"))
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Projecting population for country code: %s until %s with method %s", country.Alpha3, untilParam, method)
+
+	// Fetch the population history from the CountriesNow API
+	ctx, meta := cache.WithMeta(r.Context())
+	populationData, err := CountriesNow.GetPopulation(ctx, country.Alpha3)
+	if deadlineExceeded(ctx) && err != nil {
+		return errRequestTimeout
+	}
+	if err != nil {
+		return err
+	}
+
+	// Fit the model to the whole population history
+	model, err := projection.Fit(populationData.PopulationCounts, method)
+	if errors.Is(err, projection.ErrNotEnoughData) {
+		return utils.NewNotFoundError(fmt.Sprintf("not enough population data for %s to project it", country.Name), err)
+	}
+	if err != nil {
+		return utils.NewUnprocessableError(fmt.Sprintf("cannot fit a %s model to the population of %s", method, country.Name), err)
+	}
+	errDiverges := utils.NewUnprocessableError(fmt.Sprintf("the %s model of the population of %s diverges", method, country.Name), nil)
+	if !finite(model.RSquared, model.RMSE, model.Capacity) {
+		return errDiverges
+	}
+
+	// Describe the fit, and validate the projected years against the years of data
+	fit := utils.ProjectionFit{
+		Points:    len(populationData.PopulationCounts),
+		FirstYear: populationData.PopulationCounts[0].Year,
+		RSquared:  math.Round(model.RSquared*10000) / 10000,
+		RMSE:      round2(model.RMSE),
+		Capacity:  int(math.Round(model.Capacity)),
+	}
+	for _, v := range populationData.PopulationCounts {
+		fit.FirstYear = min(fit.FirstYear, v.Year)
+		fit.LastYear = max(fit.LastYear, v.Year)
+	}
+	if untilParam == "" {
+		until = fit.LastYear + utils.DefaultProjectionYears
+	}
+	if until <= fit.LastYear || until > fit.LastYear+utils.MaxProjectionYears {
+		return utils.NewInvalidInputError(fmt.Sprintf("invalid year %d, expected a year from %d to %d",
+			until, fit.LastYear+1, fit.LastYear+utils.MaxProjectionYears), nil)
+	}
+
+	// Project every year after the last year of data
+	values := make([]utils.ProjectedYearValue, 0, until-fit.LastYear)
+	for year := fit.LastYear + 1; year <= until; year++ {
+		value, lower, upper := model.Predict(year)
+		if !finite(value, lower, upper) {
+			return errDiverges
+		}
+		values = append(values, utils.ProjectedYearValue{
+			YearValue: utils.YearValue{Year: year, Value: int(math.Round(value))},
+			Lower:     int(math.Round(lower)),
+			Upper:     int(math.Round(upper)),
+		})
+	}
+
+	// Encode the response before sending the status code, so that an encoding error can still be reported
+	body, err := json.Marshal(utils.APIResponse{
+		Error:   false,
+		Message: "Population projection computed successfully",
+		Data: utils.PopulationProjection{
+			Method:     string(method),
+			Confidence: projection.Confidence,
+			Fit:        fit,
+			Values:     values,
+		},
+		Stale: meta.Stale(),
+	})
+	if err != nil {
+		return err
+	}
+
+	setCacheHeaders(w, meta)
+	w.WriteHeader(http.StatusOK)
+	_, err = w.Write(append(body, '\n'))
+	return err
+}
+
+// finite reports whether every value is a finite number within the range of the populations sent.
+func finite(values ...float64) bool {
+	for _, v := range values {
+		if math.IsNaN(v) || math.Abs(v) >= math.MaxInt64 {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,71 @@
+package handler
+
+import (
+	"github.com/SigurdRiseth/CountryInfoService/clients"
+	"github.com/SigurdRiseth/CountryInfoService/utils"
+	"net/http"
+	"testing"
+)
+
+func TestHandleProjection(t *testing.T) {
+	useFakeClients(t)
+
+	w, err := serve(HandleProjection, "/countryinfo/v1/population/ng/projection?until=2020&method=exponential",
+		map[string]string{"country": "ng"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, w.Code)
+	}
+
+	var projection utils.PopulationProjection
+	decodeResponse(t, w, &projection)
+	if projection.Method != "exponential" || projection.Fit.Points != 59 || projection.Fit.LastYear != 2018 {
+		t.Errorf("unexpected fit %+v", projection.Fit)
+	}
+	if len(projection.Values) != 2 || projection.Values[0].Year != 2019 || projection.Values[1].Year != 2020 {
+		t.Fatalf("expected the years 2019 and 2020, got %+v", projection.Values)
+	}
+	if v := projection.Values[1]; v.Lower > v.Value || v.Upper < v.Value || v.Value < 195874740 {
+		t.Errorf("expected a growing projection within its band, got %+v", v)
+	}
+}
+
+func TestHandleProjectionUnprocessable(t *testing.T) {
+	_, countriesNow := useFakeClients(t)
+	countriesNow.Population["SWE"] = clients.PopulationData{Iso3: "SWE", PopulationCounts: []utils.YearValue{
+		{Year: 2000, Value: 0}, {Year: 2001, Value: 10}, {Year: 2002, Value: 20},
+	}}
+	countriesNow.Population["DNK"] = clients.PopulationData{Iso3: "DNK", PopulationCounts: []utils.YearValue{
+		{Year: 2000, Value: 10}, {Year: 2000, Value: 20}, {Year: 2000, Value: 30},
+	}}
+
+	tests := []struct {
+		name    string
+		country string
+		method  string
+	}{
+		{"zero population", "se", "exponential"},
+		{"diverging fit", "dk", "linear"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w, err := serve(HandleProjection, "/countryinfo/v1/population/"+tt.country+"/projection?method="+tt.method,
+				map[string]string{"country": tt.country})
+			assertErrorKind(t, err, utils.KindUnprocessable)
+			if w.Body.Len() != 0 {
+				t.Errorf("expected no response to be sent, got %s", w.Body)
+			}
+		})
+	}
+}
+
+func TestHandleProjectionInvalidInput(t *testing.T) {
+	useFakeClients(t)
+
+	for _, query := range []string{"?method=quadratic", "?until=soon", "?until=2018", "?until=2200"} {
+		_, err := serve(HandleProjection, "/countryinfo/v1/population/ng/projection"+query, map[string]string{"country": "ng"})
+		assertErrorKind(t, err, utils.KindInvalidInput)
+	}
+}
@@ -0,0 +1,87 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/SigurdRiseth/CountryInfoService/iso"
+	"github.com/SigurdRiseth/CountryInfoService/utils"
+	"log"
+	"net/http"
+	"strconv"
+)
+
+// HandleSearch processes type-ahead searches for countries by code or name.
+//
+// Request Parameters:
+//   - "q" (query parameter): The text typed so far (e.g., "nor"), matched against the codes, common names,
+//     official names, native names and alternative spellings of every country.
+//   - "limit" (query parameter, optional): The maximum number of results, up to utils.MaxSearchLimit.
+//
+// Response:
+//   - Returns a JSON response containing the matching countries, best match first (see iso.Search).
+//     Every result carries the alpha-2 and alpha-3 codes and the flag emoji of the country.
+//
+// HTTP Status Codes:
+//   - 200 OK: The search succeeded, possibly without any results.
+//   - 400 Bad Request: The query is empty or the limit is invalid.
+//
+// Example Usage:
+//
+//	GET /countryinfo/v1/search?q=nor&limit=3 -> Norway, Norfolk Island and North Korea.
+//
+// The search is served from the embedded country table, so no upstream API is called.
+func HandleSearch(w http.ResponseWriter, r *http.Request) error {
+	w.Header().Set("Content-Type", "application/json")
+
+	query := r.URL.Query().Get("q")
+	if query == "" {
+		return utils.NewInvalidInputError("missing search query, expected e.g. '?q=nor'", nil)
+	}
+	limit, err := parseSearchLimit(r.URL.Query().Get("limit"))
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Searching countries for '%s' with limit %d", query, limit)
+
+	results := []utils.SearchResult{}
+	for _, match := range iso.Search(query, limit) {
+		results = append(results, utils.SearchResult{
+			Name:      match.Country.Name,
+			Alpha2:    match.Country.Alpha2,
+			Alpha3:    match.Country.Alpha3,
+			Flag:      match.Country.Flag(),
+			Matched:   match.Name,
+			MatchedOn: match.Kind.String(),
+		})
+	}
+
+	// The results only change when the service is redeployed
+	w.Header().Set("Cache-Control", "public, max-age=86400")
+	w.WriteHeader(http.StatusOK)
+	return json.NewEncoder(w).Encode(utils.APIResponse{
+		Error:   false,
+		Message: fmt.Sprintf("Found %d matching countries", len(results)),
+		Data:    results,
+	})
+}
+
+// parseSearchLimit parses the limit of a search, defaulting to utils.DefaultSearchLimit if not provided.
+//
+// Parameters:
+//   - limitString (string): The limit as a string; expected to be an integer from 1 to utils.MaxSearchLimit.
+//
+// Returns:
+//   - int: The limit.
+//   - error: An invalid input utils.APIError if the limit is not a valid number.
+func parseSearchLimit(limitString string) (int, error) {
+	if limitString == "" {
+		return utils.DefaultSearchLimit, nil
+	}
+	limit, err := strconv.Atoi(limitString)
+	if err != nil || limit <= 0 || limit > utils.MaxSearchLimit {
+		return 0, utils.NewInvalidInputError(
+			fmt.Sprintf("invalid limit '%s', expected a number from 1 to %d", limitString, utils.MaxSearchLimit), err)
+	}
+	return limit, nil
+}
@@ -0,0 +1,183 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/SigurdRiseth/CountryInfoService/cache"
+	"github.com/SigurdRiseth/CountryInfoService/clients"
+	"github.com/SigurdRiseth/CountryInfoService/iso"
+	"github.com/SigurdRiseth/CountryInfoService/utils"
+	"log"
+	"net/http"
+	"strings"
+)
+
+// stateSuffixes are the designations left out when matching state names, so that e.g. "Kano" matches "Kano State".
+var stateSuffixes = []string{" state", " province", " region", " county", " district", " department"}
+
+// HandleStates processes requests to list the states (or other first-level subdivisions) of a country.
+//
+// Request Parameters:
+//   - "country" (path parameter): The code or name of the country (see resolveCountry).
+//
+// Response:
+//   - Returns a JSON response containing a utils.StateList with the name and code of every state.
+//
+// HTTP Status Codes:
+//   - 200 OK: Successfully retrieved the states.
+//   - 400 Bad Request: Unknown country.
+//   - 404 Not Found: No states exist for the country.
+//   - 502 Bad Gateway / 503 Service Unavailable: The CountriesNow API answered unexpectedly or cannot be reached.
+//   - 504 Gateway Timeout: The request deadline passed before the CountriesNow API answered.
+//
+// Example Usage:
+//
+//	GET /countryinfo/v1/states/NG -> Retrieves the states of Nigeria.
+//
+// Returns a utils.APIError if fetching the states fails, leaving the error response to the caller.
+func HandleStates(w http.ResponseWriter, r *http.Request) error {
+	w.Header().Set("Content-Type", "application/json")
+
+	country, err := resolveCountry(r.PathValue("country"))
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Fetching states for country code: %s", country.Alpha2)
+
+	ctx, meta := cache.WithMeta(r.Context())
+	states, err := CountriesNow.GetStates(ctx, country.Name)
+	if deadlineExceeded(ctx) && err != nil {
+		return errRequestTimeout
+	}
+	if err != nil {
+		return err
+	}
+
+	list := utils.StateList{Country: country.Alpha2, States: make([]utils.State, 0, len(states))}
+	for _, state := range states {
+		list.States = append(list.States, utils.State{Name: state.Name, Code: state.StateCode})
+	}
+
+	setCacheHeaders(w, meta)
+	w.WriteHeader(http.StatusOK)
+	return json.NewEncoder(w).Encode(utils.APIResponse{
+		Error:   false,
+		Message: "States retrieved successfully",
+		Data:    list,
+		Stale:   meta.Stale(),
+	})
+}
+
+// HandleStateCities processes requests to list the cities of a state of a country.
+//
+// Request Parameters:
+//   - "country" (path parameter): The code or name of the country (see resolveCountry).
+//   - "state" (path parameter): The name or code of the state, ignoring case and designations
+//     such as "State" or "Province" (e.g., "Kano", "Kano State" or "KN" for Kano State in Nigeria).
+//   - "sort" (query parameter, optional): The alphabetical order of the cities, "asc" (default) or "desc".
+//
+// Response:
+//   - Returns a JSON response containing a utils.StateCities with the deduplicated and sorted cities of the state.
+//
+// HTTP Status Codes:
+//   - 200 OK: Successfully retrieved the cities.
+//   - 400 Bad Request: Unknown country or invalid sort order.
+//   - 404 Not Found: The state does not exist in the country.
+//   - 502 Bad Gateway / 503 Service Unavailable: The CountriesNow API answered unexpectedly or cannot be reached.
+//   - 504 Gateway Timeout: The request deadline passed before the CountriesNow API answered.
+//
+// Example Usage:
+//
+//	GET /countryinfo/v1/states/NG/lagos/cities -> Retrieves the cities of Lagos State in Nigeria.
+//
+// Returns a utils.APIError if fetching the cities fails, leaving the error response to the caller.
+func HandleStateCities(w http.ResponseWriter, r *http.Request) error {
+	w.Header().Set("Content-Type", "application/json")
+
+	descending, err := parseSortOrder(r.URL.Query().Get("sort"))
+	if err != nil {
+		return err
+	}
+	country, err := resolveCountry(r.PathValue("country"))
+	if err != nil {
+		return err
+	}
+	stateParam := r.PathValue("state")
+
+	log.Printf("Fetching cities for state %s of country code: %s", stateParam, country.Alpha2)
+
+	ctx, meta := cache.WithMeta(r.Context())
+	state, err := resolveState(ctx, country, stateParam)
+	if deadlineExceeded(ctx) && err != nil {
+		return errRequestTimeout
+	}
+	if err != nil {
+		return err
+	}
+	cities, err := CountriesNow.GetStateCities(ctx, country.Name, state.Name)
+	if deadlineExceeded(ctx) && err != nil {
+		return errRequestTimeout
+	}
+	if err != nil {
+		return err
+	}
+
+	setCacheHeaders(w, meta)
+	w.WriteHeader(http.StatusOK)
+	return json.NewEncoder(w).Encode(utils.APIResponse{
+		Error:   false,
+		Message: "State cities retrieved successfully",
+		Data: utils.StateCities{
+			Country: country.Alpha2,
+			State:   utils.State{Name: state.Name, Code: state.StateCode},
+			Cities:  sortCities(cities, country.Alpha2, descending),
+		},
+		Stale: meta.Stale(),
+	})
+}
+
+// resolveState finds the state of the country identified by input among the states known to the CountriesNow API.
+//
+// Parameters:
+//   - ctx (context.Context): The context of the incoming request, passed on to the upstream call.
+//   - country (iso.Country): The country of the state.
+//   - input (string): The name or code of the state, ignoring case and designations such as "State"
+//     (e.g., "kano" for "Kano State").
+//
+// Returns:
+//   - clients.State: The state, named as known to the CountriesNow API.
+//   - error: A not found utils.APIError wrapping clients.ErrStateNotFound if the country has no such state,
+//     or a utils.APIError if the states cannot be retrieved.
+func resolveState(ctx context.Context, country iso.Country, input string) (clients.State, error) {
+	states, err := CountriesNow.GetStates(ctx, country.Name)
+	if err != nil {
+		return clients.State{}, err
+	}
+
+	input = strings.TrimSpace(input)
+	for _, matches := range []func(clients.State) bool{
+		func(s clients.State) bool { return strings.EqualFold(s.Name, input) },
+		func(s clients.State) bool { return strings.EqualFold(s.StateCode, input) },
+		func(s clients.State) bool { return strings.EqualFold(trimStateSuffix(s.Name), trimStateSuffix(input)) },
+	} {
+		for _, state := range states {
+			if matches(state) {
+				return state, nil
+			}
+		}
+	}
+	return clients.State{}, utils.NewNotFoundError(fmt.Sprintf("state '%s' not found in %s", input, country.Name), clients.ErrStateNotFound)
+}
+
+// trimStateSuffix lower-cases a state name and removes its designation (e.g. " State") from the end.
+func trimStateSuffix(name string) string {
+	name = strings.ToLower(name)
+	for _, suffix := range stateSuffixes {
+		if strings.HasSuffix(name, suffix) {
+			return strings.TrimSuffix(name, suffix)
+		}
+	}
+	return name
+}
@@ -0,0 +1,79 @@
+package handler
+
+import (
+	"errors"
+	"github.com/SigurdRiseth/CountryInfoService/clients"
+	"github.com/SigurdRiseth/CountryInfoService/utils"
+	"net/http"
+	"testing"
+)
+
+func TestHandleStates(t *testing.T) {
+	useFakeClients(t)
+
+	w, err := serve(HandleStates, "/countryinfo/v1/states/ng", map[string]string{"country": "ng"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, w.Code)
+	}
+
+	var list utils.StateList
+	decodeResponse(t, w, &list)
+	if list.Country != "NG" || len(list.States) == 0 {
+		t.Fatalf("expected the states of Nigeria, got %+v", list)
+	}
+	if first := list.States[0]; first.Name != "Abia State" || first.Code != "AB" {
+		t.Errorf("expected Abia State (AB) first, got %+v", first)
+	}
+}
+
+func TestHandleStateCities(t *testing.T) {
+	useFakeClients(t)
+
+	for _, state := range []string{"Lagos", "lagos", "LA"} {
+		t.Run(state, func(t *testing.T) {
+			w, err := serve(HandleStateCities, "/countryinfo/v1/states/ng/"+state+"/cities?sort=desc",
+				map[string]string{"country": "ng", "state": state})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var cities utils.StateCities
+			decodeResponse(t, w, &cities)
+			if cities.State.Name != "Lagos" || len(cities.Cities) != 8 {
+				t.Fatalf("expected the 8 cities of Lagos, got %+v", cities)
+			}
+			if cities.Cities[0] != "Makoko" || cities.Cities[7] != "Apapa" {
+				t.Errorf("expected the cities in descending order, got %v", cities.Cities)
+			}
+		})
+	}
+}
+
+func TestHandleStateCitiesUnknownState(t *testing.T) {
+	useFakeClients(t)
+
+	_, err := serve(HandleStateCities, "/countryinfo/v1/states/ng/atlantis/cities",
+		map[string]string{"country": "ng", "state": "atlantis"})
+	assertErrorKind(t, err, utils.KindNotFound)
+	if !errors.Is(err, clients.ErrStateNotFound) {
+		t.Errorf("expected the state to be reported as missing, got %v", err)
+	}
+}
+
+func TestHandleStateCitiesMissingUpstream(t *testing.T) {
+	useFakeClients(t)
+
+	// Kano State is listed among the states of Nigeria, but has no registered cities
+	_, err := serve(HandleStateCities, "/countryinfo/v1/states/ng/kano/cities",
+		map[string]string{"country": "ng", "state": "kano"})
+	assertErrorKind(t, err, utils.KindNotFound)
+	if want := "state 'Kano State' not found in Nigeria"; err.(*utils.APIError).Message != want {
+		t.Errorf("expected message %q, got %q", want, err.(*utils.APIError).Message)
+	}
+	if !errors.Is(err, clients.ErrStateNotFound) {
+		t.Errorf("expected the state to be reported as missing, got %v", err)
+	}
+}
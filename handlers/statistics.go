@@ -0,0 +1,194 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/SigurdRiseth/CountryInfoService/utils"
+	"math"
+	"slices"
+	"strings"
+)
+
+// populationStats are the statistics that can be requested with the stats parameter of the population endpoint,
+// named as the fields of utils.PopulationStats.
+var populationStats = []string{"median", "min", "max", "stddev", "changes", "cagr", "doublingtime"}
+
+// allPopulationStats is the value of the stats parameter requesting every statistic.
+const allPopulationStats = "all"
+
+// parseStats parses a comma-separated list of population statistics (e.g. "median,cagr").
+//
+// Parameters:
+//   - statsString (string): The value of the stats parameter; empty requests no statistics,
+//     and "all" requests every statistic.
+//
+// Returns:
+//   - []string: The requested statistics without duplicates, in the order of populationStats,
+//     or nil if no statistics are requested.
+//   - error: An invalid input utils.APIError naming the first unknown statistic, if any.
+func parseStats(statsString string) ([]string, error) {
+	requested := make(map[string]bool)
+	for _, stat := range strings.Split(statsString, ",") {
+		stat = strings.ToLower(strings.TrimSpace(stat))
+		switch {
+		case stat == "":
+			continue
+		case stat == allPopulationStats:
+			for _, known := range populationStats {
+				requested[known] = true
+			}
+		case slices.Contains(populationStats, stat):
+			requested[stat] = true
+		default:
+			return nil, utils.NewInvalidInputError(fmt.Sprintf("unknown statistic '%s', expected '%s' or a comma-separated list of %s",
+				stat, allPopulationStats, strings.Join(populationStats, ", ")), nil)
+		}
+	}
+
+	var stats []string
+	for _, stat := range populationStats {
+		if requested[stat] {
+			stats = append(stats, stat)
+		}
+	}
+	return stats, nil
+}
+
+// selectStats computes the statistics of a population history, keeping only the requested statistics in its response.
+//
+// Parameters:
+//   - values ([]utils.YearValue): The population history, in any order.
+//   - stats ([]string): The requested statistics, as returned by parseStats.
+//
+// Returns:
+//   - *utils.SelectedPopulationStats: The requested statistics (see computePopulationStats),
+//     or nil if no statistics are requested.
+//   - error: An error if a statistic cannot be encoded.
+func selectStats(values []utils.YearValue, stats []string) (*utils.SelectedPopulationStats, error) {
+	if stats == nil {
+		return nil, nil
+	}
+
+	computed := computePopulationStats(values)
+	selected := &utils.SelectedPopulationStats{}
+	for _, stat := range stats {
+		var field *json.RawMessage
+		var value any
+		switch stat {
+		case "median":
+			field, value = &selected.Median, computed.Median
+		case "min":
+			field, value = &selected.Min, computed.Min
+		case "max":
+			field, value = &selected.Max, computed.Max
+		case "stddev":
+			field, value = &selected.StdDev, computed.StdDev
+		case "changes":
+			field, value = &selected.Changes, computed.Changes
+		case "cagr":
+			field, value = &selected.CAGR, computed.CAGR
+		case "doublingtime":
+			field, value = &selected.DoublingTime, computed.DoublingTime
+		default:
+			continue
+		}
+
+		// Undefined statistics are encoded as null, so that they are sent rather than left out
+		encoded, err := json.Marshal(value)
+		if err != nil {
+			return nil, err
+		}
+		*field = encoded
+	}
+	return selected, nil
+}
+
+// computePopulationStats computes the statistics of a population history.
+//
+// Parameters:
+//   - values ([]utils.YearValue): The population history, in any order.
+//
+// Returns:
+//   - utils.PopulationStats: Every statistic of the values. The median, extremes and standard deviation are null
+//     without values, and the CAGR and doubling time are null unless the values span more than one year
+//     and start from a positive population.
+//
+// Example Usage:
+//
+//	stats := computePopulationStats([]utils.YearValue{{Year: 2000, Value: 100}, {Year: 2010, Value: 200}})
+//	// Median 150, CAGR 7.18 (percent per year) and DoublingTime 10 (years)
+func computePopulationStats(values []utils.YearValue) utils.PopulationStats {
+	stats := utils.PopulationStats{Changes: []utils.YearChange{}}
+	if len(values) == 0 {
+		return stats
+	}
+
+	// Sort a copy by year, as the changes and growth rate depend on the order
+	byYear := slices.Clone(values)
+	slices.SortFunc(byYear, func(a, b utils.YearValue) int { return a.Year - b.Year })
+
+	// Extremes and mean, keeping the earliest year of equal extremes
+	minimum, maximum := byYear[0], byYear[0]
+	sum := 0.0
+	for _, v := range byYear {
+		if v.Value < minimum.Value {
+			minimum = v
+		}
+		if v.Value > maximum.Value {
+			maximum = v
+		}
+		sum += float64(v.Value)
+	}
+	stats.Min, stats.Max = &minimum, &maximum
+	mean := sum / float64(len(byYear))
+
+	// Population standard deviation
+	squares := 0.0
+	for _, v := range byYear {
+		squares += (float64(v.Value) - mean) * (float64(v.Value) - mean)
+	}
+	stdDev := round2(math.Sqrt(squares / float64(len(byYear))))
+	stats.StdDev = &stdDev
+
+	// Median
+	sorted := make([]int, len(byYear))
+	for i, v := range byYear {
+		sorted[i] = v.Value
+	}
+	slices.Sort(sorted)
+	median := float64(sorted[len(sorted)/2])
+	if len(sorted)%2 == 0 {
+		median = (float64(sorted[len(sorted)/2-1]) + median) / 2
+	}
+	stats.Median = &median
+
+	// Year-over-year changes
+	for i := 1; i < len(byYear); i++ {
+		previous, current := byYear[i-1], byYear[i]
+		change := utils.YearChange{Year: current.Year, Absolute: current.Value - previous.Value}
+		if previous.Value != 0 {
+			percent := round2(float64(change.Absolute) / float64(previous.Value) * 100)
+			change.Percent = &percent
+		}
+		stats.Changes = append(stats.Changes, change)
+	}
+
+	// Compound annual growth rate and doubling time
+	first, last := byYear[0], byYear[len(byYear)-1]
+	if years := last.Year - first.Year; years > 0 && first.Value > 0 {
+		growth := math.Pow(float64(last.Value)/float64(first.Value), 1/float64(years)) - 1
+		cagr := round2(growth * 100)
+		stats.CAGR = &cagr
+		if growth > 0 {
+			doublingTime := round2(math.Log(2) / math.Log1p(growth))
+			stats.DoublingTime = &doublingTime
+		}
+	}
+
+	return stats
+}
+
+// round2 rounds x to two decimal places.
+func round2(x float64) float64 {
+	return math.Round(x*100) / 100
+}
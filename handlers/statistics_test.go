@@ -0,0 +1,114 @@
+package handler
+
+import (
+	"encoding/json"
+	"github.com/SigurdRiseth/CountryInfoService/utils"
+	"net/http"
+	"slices"
+	"testing"
+)
+
+func TestParseStats(t *testing.T) {
+	tests := []struct {
+		input string
+		want  []string
+	}{
+		{"", nil},
+		{" , ", nil},
+		{"cagr,median,CAGR", []string{"median", "cagr"}},
+		{"all", populationStats},
+		{"min, all", populationStats},
+	}
+	for _, tt := range tests {
+		got, err := parseStats(tt.input)
+		if err != nil {
+			t.Fatalf("parseStats(%q): %v", tt.input, err)
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("parseStats(%q) = %v, expected %v", tt.input, got, tt.want)
+		}
+	}
+
+	_, err := parseStats("median,mode")
+	assertErrorKind(t, err, utils.KindInvalidInput)
+}
+
+func TestComputePopulationStats(t *testing.T) {
+	stats := computePopulationStats([]utils.YearValue{
+		{Year: 2010, Value: 200},
+		{Year: 2000, Value: 100},
+		{Year: 2005, Value: 100},
+	})
+
+	if *stats.Median != 100 || stats.Min.Year != 2000 || stats.Max.Year != 2010 {
+		t.Errorf("unexpected median and extremes: %v, %+v, %+v", *stats.Median, *stats.Min, *stats.Max)
+	}
+	if *stats.StdDev != 47.14 {
+		t.Errorf("expected standard deviation 47.14, got %v", *stats.StdDev)
+	}
+	if len(stats.Changes) != 2 || stats.Changes[0].Absolute != 0 || *stats.Changes[1].Percent != 100 {
+		t.Errorf("unexpected changes %+v", stats.Changes)
+	}
+	if *stats.CAGR != 7.18 || *stats.DoublingTime != 10 {
+		t.Errorf("expected CAGR 7.18 and doubling time 10, got %v and %v", *stats.CAGR, *stats.DoublingTime)
+	}
+}
+
+func TestComputePopulationStatsUndefined(t *testing.T) {
+	empty := computePopulationStats(nil)
+	if empty.Median != nil || empty.Min != nil || empty.StdDev != nil || empty.CAGR != nil || len(empty.Changes) != 0 {
+		t.Errorf("expected undefined statistics without values, got %+v", empty)
+	}
+
+	shrinking := computePopulationStats([]utils.YearValue{{Year: 2000, Value: 200}, {Year: 2001, Value: 100}})
+	if shrinking.CAGR == nil || *shrinking.CAGR != -50 || shrinking.DoublingTime != nil {
+		t.Errorf("expected a CAGR of -50 without doubling time, got %+v", shrinking)
+	}
+}
+
+func TestHandlePopulationStats(t *testing.T) {
+	useFakeClients(t)
+
+	w, err := serve(HandlePopulation, "/countryinfo/v1/population/ng?limit=2018&stats=cagr,median",
+		map[string]string{"country": "ng"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, w.Code)
+	}
+
+	// Only the requested statistics are sent, and the CAGR of a single year is null
+	var population struct {
+		Stats map[string]json.RawMessage `json:"stats"`
+	}
+	decodeResponse(t, w, &population)
+	if len(population.Stats) != 2 || string(population.Stats["median"]) != "195874740" || string(population.Stats["cagr"]) != "null" {
+		t.Errorf("expected the median and a null CAGR only, got %v", population.Stats)
+	}
+}
+
+func TestSelectStats(t *testing.T) {
+	values := []utils.YearValue{{Year: 2000, Value: 100}}
+	if selected, err := selectStats(values, nil); selected != nil || err != nil {
+		t.Errorf("expected no statistics unless requested, got %+v (%v)", selected, err)
+	}
+
+	stats, err := parseStats("doublingtime,min,median")
+	if err != nil {
+		t.Fatal(err)
+	}
+	selected, err := selectStats(values, stats)
+	if err != nil {
+		t.Fatal(err)
+	}
+	encoded, err := json.Marshal(selected)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Requested statistics keep their declared order, with null for the undefined doubling time
+	if want := `{"median":100,"min":{"year":2000,"value":100},"doublingtime":null}`; string(encoded) != want {
+		t.Errorf("expected %s, got %s", want, encoded)
+	}
+}
@@ -1,7 +1,9 @@
 package handler
 
 import (
+	"context"
 	"encoding/json"
+	"github.com/SigurdRiseth/CountryInfoService/clients"
 	"github.com/SigurdRiseth/CountryInfoService/utils"
 	"log"
 	"net/http"
@@ -29,7 +31,10 @@ var StartTime time.Time
 // Behavior:
 //   - The function retrieves the status of the CountriesNow API and the RestCountries API by calling `getAPIStatus`.
 //   - It then generates a response containing the statuses of both APIs along with the uptime of the service.
-//   - The response is sent back to the client in JSON format with an HTTP status code of 200 (OK).
+//   - If caching is enabled, the hit and miss counters of the response cache are included as well.
+//   - The state and last transition time of the circuit breaker of every upstream API are included.
+//   - The response is sent back to the client in JSON format with an HTTP status code of 200 (OK),
+//     or 504 (Gateway Timeout) if the request deadline passed while checking the external APIs.
 //
 // Note:
 //   - This function does not handle individual API errors beyond the request failure (e.g., network issues).
@@ -41,10 +46,39 @@ func HandleStatus(w http.ResponseWriter, r *http.Request) error {
 
 	// Fetch statuses of external APIs
 	apiStatuses := utils.NewAPIStatus(
-		getAPIStatus(utils.CountriesNowApiUrl),
-		getAPIStatus(utils.RestCountriesApiUrl),
+		getAPIStatus(r.Context(), CountriesNowUpstream, "CountriesNow", utils.CountriesNowApiUrl),
+		getAPIStatus(r.Context(), RestCountriesUpstream, "RestCountries", utils.RestCountriesApiUrl),
 		time.Since(StartTime).Seconds(),
 	)
+	if deadlineExceeded(r.Context()) {
+		return errRequestTimeout
+	}
+
+	// Report the cache counters if caching is enabled
+	if Cache != nil {
+		stats := Cache.Stats()
+		apiStatuses.Cache = &utils.CacheStatus{
+			Hits:                 stats.Hits,
+			Misses:               stats.Misses,
+			Stale:                stats.Stale,
+			Entries:              stats.Entries,
+			MaxEntries:           stats.MaxSize,
+			TTL:                  stats.TTL.String(),
+			StaleWhileRevalidate: stats.StaleWhileRevalidate.String(),
+			Persistent:           stats.Persistent,
+		}
+	}
+
+	// Report the state of the circuit breakers
+	for _, breaker := range Breakers {
+		snapshot := breaker.Snapshot()
+		apiStatuses.Breakers = append(apiStatuses.Breakers, utils.BreakerStatus{
+			API:            snapshot.Name,
+			State:          snapshot.State.String(),
+			Failures:       snapshot.Failures,
+			LastTransition: snapshot.LastTransition.UTC().Format(time.RFC3339),
+		})
+	}
 
 	// Create API response
 	resp := utils.APIResponse{
@@ -65,24 +99,34 @@ func HandleStatus(w http.ResponseWriter, r *http.Request) error {
 	return err
 }
 
-// getAPIStatus checks the availability of an API by sending a GET request to the provided URL.
+// getAPIStatus checks the availability of an API by sending a GET request to the provided URL
+// through the shared HTTP client of the API, retrying failed attempts like every other upstream call.
 //
 // Parameters:
+//   - ctx (context.Context): The context of the incoming request, cancelling the check when done.
+//   - upstream (*clients.Upstream): The shared HTTP client of the API; if nil, a client with
+//     clients.DefaultRetryPolicy and without a circuit breaker is used.
+//   - name (string): The name of the API, used in log messages.
 //   - apiURL (string): The URL of the API to check.
 //
 // Returns:
 //   - string: A predefined message indicating whether the API is online or offline.
 //
 // Behavior:
-//   - If the request fails (e.g., network error, timeout), it logs the error and returns `OfflineMessage`.
+//   - If the request fails after all retries (e.g., network error, timeout), or the circuit breaker of the API
+//     is open, it logs the error and returns `OfflineMessage`.
 //   - If the request succeeds (regardless of status code), it returns `OnlineMessage`.
 //
 // Note:
 //   - This function does not differentiate between different HTTP status codes (e.g., 404 vs. 200).
 //   - The API is considered "online" as long as the server responds to the request.
-func getAPIStatus(apiURL string) string {
+func getAPIStatus(ctx context.Context, upstream *clients.Upstream, name, apiURL string) string {
+	if upstream == nil {
+		upstream = clients.NewUpstream(name, clients.DefaultRetryPolicy, nil, nil)
+	}
+
 	// Send a GET request to the API
-	resp, err := http.Get(apiURL)
+	resp, err := upstream.Do(ctx, http.MethodGet, apiURL, nil)
 	if err != nil {
 		log.Printf("Error contacting API: %v", err)
 		return OfflineMessage
@@ -0,0 +1,47 @@
+package handler
+
+import (
+	"context"
+	"github.com/SigurdRiseth/CountryInfoService/clients"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestGetAPIStatusRetries(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	policy := clients.RetryPolicy{Timeout: time.Second, MaxRetries: 1, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond}
+	upstream := clients.NewUpstream("test", policy, nil, server.Client())
+	if status := getAPIStatus(context.Background(), upstream, "test", server.URL); status != OnlineMessage {
+		t.Errorf("expected %s, got %s", OnlineMessage, status)
+	}
+	if requests.Load() != 2 {
+		t.Errorf("expected the failed check to be retried, got %d requests", requests.Load())
+	}
+}
+
+func TestGetAPIStatusOffline(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	server.Close() // Nothing is listening anymore
+
+	policy := clients.RetryPolicy{Timeout: time.Second, MaxRetries: 1, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond}
+	if status := getAPIStatus(context.Background(), clients.NewUpstream("test", policy, nil, nil), "test", server.URL); status != OfflineMessage {
+		t.Errorf("expected %s, got %s", OfflineMessage, status)
+	}
+
+	// An open circuit breaker reports the API as offline without calling it
+	breaker := clients.NewBreaker("test", clients.BreakerSettings{FailureThreshold: 1, Cooldown: time.Hour})
+	breaker.Record(false)
+	if status := getAPIStatus(context.Background(), clients.NewUpstream("test", policy, breaker, nil), "test", server.URL); status != OfflineMessage {
+		t.Errorf("expected %s while the breaker is open, got %s", OfflineMessage, status)
+	}
+}
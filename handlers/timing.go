@@ -0,0 +1,34 @@
+package handler
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// serverTiming collects the duration of the upstream calls made while serving a request,
+// to be reported in the Server-Timing response header. It is safe for concurrent use.
+type serverTiming struct {
+	mu      sync.Mutex
+	metrics []string
+}
+
+// newServerTiming creates an empty serverTiming.
+func newServerTiming() *serverTiming {
+	return &serverTiming{}
+}
+
+// add records that the call named name took duration.
+func (t *serverTiming) add(name string, duration time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.metrics = append(t.metrics, fmt.Sprintf("%s;dur=%.1f", name, float64(duration.Microseconds())/1000))
+}
+
+// String formats the recorded durations as the value of a Server-Timing header.
+func (t *serverTiming) String() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return strings.Join(t.metrics, ", ")
+}
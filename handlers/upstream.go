@@ -0,0 +1,71 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/SigurdRiseth/CountryInfoService/cache"
+	"github.com/SigurdRiseth/CountryInfoService/clients"
+	"github.com/SigurdRiseth/CountryInfoService/utils"
+	"net/http"
+)
+
+// Upstream clients used by the handlers. StartServer injects the live HTTP
+// implementations; tests can inject clients.FakeRestCountries and
+// clients.FakeCountriesNow loaded from tests/mockdata instead.
+var (
+	RestCountries clients.RestCountriesClient
+	CountriesNow  clients.CountriesNowClient
+)
+
+// Shared HTTP clients of the upstream APIs, used by the status endpoint to check their availability
+// with the same timeouts, retries and circuit breakers as the other calls. StartServer injects them;
+// if nil, a client with clients.DefaultRetryPolicy is used.
+var (
+	RestCountriesUpstream *clients.Upstream
+	CountriesNowUpstream  *clients.Upstream
+)
+
+// Breakers are the circuit breakers of the upstream APIs, reported by the status endpoint.
+var Breakers []*clients.Breaker
+
+// Cache is the response cache in front of the upstream clients, or nil if caching is disabled.
+// It is only used to report the cache counters in the status endpoint.
+var Cache *cache.Cache
+
+// errRequestTimeout is reported when the request deadline passes before the upstream APIs answer.
+var errRequestTimeout = utils.NewTimeoutError("", "request timed out waiting for upstream APIs", context.DeadlineExceeded)
+
+// deadlineExceeded reports whether the deadline of the request context has passed.
+func deadlineExceeded(ctx context.Context) bool {
+	return errors.Is(ctx.Err(), context.DeadlineExceeded)
+}
+
+// setCacheHeaders sets the Cache-Control and Age headers of a successful response
+// based on the cached upstream values recorded in meta.
+//
+// If an expired value was served, the response is flagged with the X-Data-Stale and Warning headers.
+// If no cached value was used (e.g. caching is disabled), the response is marked as not cacheable.
+func setCacheHeaders(w http.ResponseWriter, meta *cache.Meta) {
+	maxAge, ok := meta.MaxAge()
+	if !ok {
+		w.Header().Set("Cache-Control", "no-cache")
+		return
+	}
+
+	cacheControl := fmt.Sprintf("public, max-age=%d", int(maxAge.Seconds()))
+	if window := meta.StaleWhileRevalidate(); window > 0 {
+		cacheControl += fmt.Sprintf(", stale-while-revalidate=%d", int(window.Seconds()))
+	}
+	w.Header().Set("Cache-Control", cacheControl)
+	w.Header().Set("Age", fmt.Sprintf("%d", int(meta.Age().Seconds())))
+
+	if meta.Stale() {
+		w.Header().Set("X-Data-Stale", "true")
+		if meta.RevalidationFailed() {
+			w.Header().Set("Warning", `111 - "Revalidation Failed"`)
+		} else {
+			w.Header().Set("Warning", `110 - "Response is Stale"`)
+		}
+	}
+}
@@ -0,0 +1,27 @@
+package handler
+
+import (
+	"github.com/SigurdRiseth/CountryInfoService/cache"
+	"github.com/SigurdRiseth/CountryInfoService/clients"
+	"testing"
+	"time"
+)
+
+func TestSetCacheHeadersWithoutReportedCache(t *testing.T) {
+	_, countriesNow := useFakeClients(t)
+
+	// Cached clients are injected, but the cache itself is not reported to the status endpoint
+	responses := cache.New(time.Hour, 10)
+	responses.SetStaleWhileRevalidate(time.Minute)
+	previousCountriesNow, previousCache := CountriesNow, Cache
+	CountriesNow, Cache = clients.NewCachedCountriesNow(countriesNow, responses), nil
+	t.Cleanup(func() { CountriesNow, Cache = previousCountriesNow, previousCache })
+
+	w, err := serve(HandlePopulation, "/countryinfo/v1/population/ng", map[string]string{"country": "ng"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "public, max-age=3600, stale-while-revalidate=60"; w.Header().Get("Cache-Control") != want {
+		t.Errorf("expected Cache-Control %q, got %q", want, w.Header().Get("Cache-Control"))
+	}
+}
@@ -0,0 +1,183 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"github.com/SigurdRiseth/CountryInfoService/utils"
+	"math"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// lastYearsPrefix starts a relative year range, e.g. "last:10" for the last 10 years of data.
+const lastYearsPrefix = "last:"
+
+// yearRange is an inclusive range of years selected by the limit parameter.
+type yearRange struct {
+	start, end int // The first and last year, math.MinInt and math.MaxInt for open bounds
+	last       int // The number of years up to the last year of data, for relative ranges; zero otherwise
+}
+
+// parseYearLimit parses the limit parameter of the population endpoints: a comma-separated list of year ranges.
+//
+// Every range takes one of the forms:
+//   - "2000-2010": The years from 2000 to 2010.
+//   - "2000-": The years from 2000 on.
+//   - "-1990": The years until 1990.
+//   - "2010": The year 2010 only.
+//   - "last:10": The last 10 years up to the last year of data.
+//
+// Parameters:
+//   - limit (string): The value of the limit parameter; empty selects every year.
+//
+// Returns:
+//   - []yearRange: The ranges, or nil if every year is selected.
+//   - error: An invalid input utils.APIError naming the offending part of the limit and its position, if any.
+//
+// Example Usage:
+//
+//	ranges, err := parseYearLimit("-1970,2000-2002,last:5")
+func parseYearLimit(limit string) ([]yearRange, error) {
+	if limit == "" {
+		return nil, nil
+	}
+
+	var ranges []yearRange
+	offset := 0
+	for _, part := range strings.Split(limit, ",") {
+		token := strings.TrimSpace(part)
+		tokenOffset := offset + strings.Index(part, token)
+		offset += len(part) + 1
+
+		r, errOffset, err := parseYearRange(token)
+		if err != nil {
+			position := utf8.RuneCountInString(limit[:tokenOffset+errOffset]) + 1
+			return nil, utils.NewInvalidInputError(fmt.Sprintf("invalid limit '%s' at position %d: %s", limit, position, err), nil)
+		}
+		ranges = append(ranges, r)
+	}
+	return ranges, nil
+}
+
+// parseYearRange parses a single range of the limit parameter (see parseYearLimit).
+//
+// Returns:
+//   - yearRange: The range.
+//   - int: The byte offset within the token of the part at fault, if the range is invalid.
+//   - error: An error describing what is wrong with the range.
+func parseYearRange(token string) (yearRange, int, error) {
+	if token == "" {
+		return yearRange{}, 0, errors.New("empty range")
+	}
+
+	if strings.HasPrefix(strings.ToLower(token), lastYearsPrefix) {
+		count := token[len(lastYearsPrefix):]
+		n, err := parseYear(count)
+		if err != nil || n < 1 {
+			return yearRange{}, len(lastYearsPrefix), fmt.Errorf("invalid number of years '%s', expected a positive integer", count)
+		}
+		return yearRange{last: n}, 0, nil
+	}
+
+	startString, endString, isRange := strings.Cut(token, "-")
+	if !isRange {
+		year, err := parseYear(token)
+		if err != nil {
+			return yearRange{}, 0, fmt.Errorf("invalid year '%s', expected an integer, 'start-end', 'start-', '-end' or '%sN'", token, lastYearsPrefix)
+		}
+		return yearRange{start: year, end: year}, 0, nil
+	}
+	if startString == "" && endString == "" {
+		return yearRange{}, 0, errors.New("range '-' has neither a start nor an end year")
+	}
+
+	r := yearRange{start: math.MinInt, end: math.MaxInt}
+	if startString != "" {
+		year, err := parseYear(startString)
+		if err != nil {
+			return yearRange{}, 0, fmt.Errorf("invalid start year '%s', expected an integer", startString)
+		}
+		r.start = year
+	}
+	if endString != "" {
+		year, err := parseYear(endString)
+		if err != nil {
+			return yearRange{}, len(startString) + 1, fmt.Errorf("invalid end year '%s', expected an integer", endString)
+		}
+		r.end = year
+	}
+	if r.start > r.end {
+		return yearRange{}, 0, fmt.Errorf("start year %d cannot be greater than end year %d", r.start, r.end)
+	}
+	return r, 0, nil
+}
+
+// parseYear parses a year (or a number of years), rejecting signs and surrounding spaces.
+func parseYear(s string) (int, error) {
+	if s == "" || strings.TrimLeft(s, "0123456789") != "" {
+		return 0, errors.New("not a year")
+	}
+	return strconv.Atoi(s)
+}
+
+// filterByYearRanges keeps the values whose year lies in any of the ranges, in their original order.
+//
+// Parameters:
+//   - values ([]utils.YearValue): The population history to filter.
+//   - ranges ([]yearRange): The ranges selected by the limit parameter, resolved against the population history
+//     (see resolveYearRanges); nil keeps every value.
+//
+// Returns:
+//   - []utils.YearValue: The values within the ranges, or the original values if ranges is nil.
+//
+// Example Usage:
+//
+//	values := []utils.YearValue{{Year: 2000, Value: 100}, {Year: 2005, Value: 150}, {Year: 2010, Value: 200}}
+//	ranges, _ := parseYearLimit("-2000,last:1")
+//	filtered := filterByYearRanges(values, resolveYearRanges(ranges, values)) // The values of 2000 and 2010
+func filterByYearRanges(values []utils.YearValue, ranges []yearRange) []utils.YearValue {
+	if ranges == nil {
+		return values
+	}
+
+	var filtered []utils.YearValue
+	for _, v := range values {
+		for _, r := range ranges {
+			if v.Year >= r.start && v.Year <= r.end {
+				filtered = append(filtered, v)
+				break
+			}
+		}
+	}
+	return filtered
+}
+
+// resolveYearRanges converts the relative ranges (e.g. "last:10") into absolute ranges ending in the last year of data.
+// The ranges are resolved once against the data as fetched, so that years filled in after the last year of data
+// (see fillMissingYears) do not shift them.
+//
+// Parameters:
+//   - ranges ([]yearRange): The ranges selected by the limit parameter (see parseYearLimit).
+//   - values ([]utils.YearValue): The population history, in any order.
+//
+// Returns:
+//   - []yearRange: The absolute ranges, or nil if ranges is nil.
+func resolveYearRanges(ranges []yearRange, values []utils.YearValue) []yearRange {
+	if ranges == nil {
+		return nil
+	}
+	lastYear := math.MinInt
+	for _, v := range values {
+		lastYear = max(lastYear, v.Year)
+	}
+
+	resolved := make([]yearRange, len(ranges))
+	for i, r := range ranges {
+		if r.last > 0 {
+			r = yearRange{start: lastYear - r.last + 1, end: lastYear}
+		}
+		resolved[i] = r
+	}
+	return resolved
+}
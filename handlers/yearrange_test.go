@@ -0,0 +1,77 @@
+package handler
+
+import (
+	"github.com/SigurdRiseth/CountryInfoService/utils"
+	"math"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestParseYearLimit(t *testing.T) {
+	tests := []struct {
+		limit string
+		want  []yearRange
+	}{
+		{"", nil},
+		{"2000-2010", []yearRange{{start: 2000, end: 2010}}},
+		{"2000-", []yearRange{{start: 2000, end: math.MaxInt}}},
+		{"-1990", []yearRange{{start: math.MinInt, end: 1990}}},
+		{"2010", []yearRange{{start: 2010, end: 2010}}},
+		{"LAST:5", []yearRange{{last: 5}}},
+		{" -1970 , 2000-2002,last:5", []yearRange{{start: math.MinInt, end: 1970}, {start: 2000, end: 2002}, {last: 5}}},
+	}
+	for _, tt := range tests {
+		got, err := parseYearLimit(tt.limit)
+		if err != nil {
+			t.Fatalf("parseYearLimit(%q): %v", tt.limit, err)
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("parseYearLimit(%q) = %+v, expected %+v", tt.limit, got, tt.want)
+		}
+	}
+}
+
+func TestParseYearLimitErrors(t *testing.T) {
+	tests := []struct {
+		limit    string
+		position string
+	}{
+		{"2010-2000", "position 1"},
+		{"2000,,2010", "position 6"},
+		{"2000-20x0", "position 6"},
+		{"x-2000", "position 1"},
+		{"-", "position 1"},
+		{"+2000", "position 1"},
+		{"2000,last:0", "position 11"},
+		{"last:+3", "position 6"},
+		{"last:-3", "position 6"},
+		{"last: 3", "position 6"},
+		{"lást:3", "position 1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.limit, func(t *testing.T) {
+			_, err := parseYearLimit(tt.limit)
+			assertErrorKind(t, err, utils.KindInvalidInput)
+			if !strings.Contains(err.Error(), tt.position) {
+				t.Errorf("expected the error to be reported at %s, got %v", tt.position, err)
+			}
+		})
+	}
+}
+
+func TestFilterByYearRanges(t *testing.T) {
+	values := []utils.YearValue{{Year: 2000, Value: 100}, {Year: 2005, Value: 150}, {Year: 2010, Value: 200}}
+	ranges, err := parseYearLimit("-2000,last:1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	filtered := filterByYearRanges(values, resolveYearRanges(ranges, values))
+	if len(filtered) != 2 || filtered[0].Year != 2000 || filtered[1].Year != 2010 {
+		t.Errorf("expected the values of 2000 and 2010, got %+v", filtered)
+	}
+	if all := filterByYearRanges(values, nil); len(all) != len(values) {
+		t.Errorf("expected every value without ranges, got %+v", all)
+	}
+}
@@ -0,0 +1,275 @@
+// Package iso provides the ISO 3166-1 country code table embedded in the service,
+// and resolves the different ways clients identify a country to a table entry.
+//
+// The table (countries.json) is generated from the mledoze/countries dataset that
+// RestCountries is built on, so every code known here is also known upstream.
+// Kosovo is included under its user-assigned code XK, as RestCountries serves it.
+package iso
+
+import (
+	_ "embed"
+	"encoding/json"
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+//go:embed countries.json
+var countriesJSON []byte
+
+// Country is an entry of the ISO 3166-1 country code table.
+type Country struct {
+	Alpha2   string   `json:"alpha2"`
+	Alpha3   string   `json:"alpha3"`
+	Numeric  string   `json:"numeric"` // Empty for user-assigned codes
+	Name     string   `json:"name"`    // The common English name
+	Official string   `json:"official"`
+	Native   []string `json:"native"` // Common and official names in the official languages
+	Alt      []string `json:"alt"`    // Alternative spellings: the common name in other languages
+}
+
+// Flag returns the flag emoji of the country, made up of the regional indicator symbols of its alpha-2 code.
+func (c Country) Flag() string {
+	var flag strings.Builder
+	for _, letter := range c.Alpha2 {
+		flag.WriteRune('🇦' + letter - 'A')
+	}
+	return flag.String()
+}
+
+var (
+	countries []Country
+	byKey     map[string]Country // Countries by folded code or name, see Resolve
+)
+
+// aliases maps abbreviations and former names that clients commonly use, but that are neither codes
+// nor names in the table, to the alpha-2 code of the country. Aliases are resolved, but not searched.
+var aliases = map[string]string{
+	"UK":                    "GB",
+	"Great Britain":         "GB",
+	"Britain":               "GB",
+	"America":               "US",
+	"UAE":                   "AE",
+	"DRC":                   "CD",
+	"Congo-Kinshasa":        "CD",
+	"Congo-Brazzaville":     "CG",
+	"Czech Republic":        "CZ",
+	"Macedonia":             "MK",
+	"Republic of Macedonia": "MK",
+	"Swaziland":             "SZ",
+	"Kingdom of Swaziland":  "SZ",
+	"Burma":                 "MM",
+	"Holland":               "NL",
+	"East Timor":            "TL",
+	"Vatican":               "VA",
+	"Holy See":              "VA",
+}
+
+func init() {
+	if err := json.Unmarshal(countriesJSON, &countries); err != nil {
+		panic("iso: decoding embedded country table: " + err.Error())
+	}
+	byKey = buildIndex(countries)
+	searchIndex = buildSearchIndex(countries)
+}
+
+// buildIndex maps the folded codes and names of the countries to the countries.
+// Keys are added in order of precedence (codes, common names, official names, aliases, native names),
+// so that e.g. the code of one country is never shadowed by the native name of another.
+// Keys shared by several countries at the same precedence are ambiguous and left out.
+func buildIndex(countries []Country) map[string]Country {
+	aliasesOf := make(map[string][]string)
+	for alias, alpha2 := range aliases {
+		aliasesOf[alpha2] = append(aliasesOf[alpha2], alias)
+	}
+
+	index := make(map[string]Country)
+	levels := []func(Country) []string{
+		func(c Country) []string { return []string{c.Alpha2, c.Alpha3, c.Numeric} },
+		func(c Country) []string { return []string{c.Name} },
+		func(c Country) []string { return []string{c.Official} },
+		func(c Country) []string { return aliasesOf[c.Alpha2] },
+		func(c Country) []string { return c.Native },
+	}
+
+	for _, keysOf := range levels {
+		level := make(map[string]Country)
+		ambiguous := make(map[string]bool)
+		for _, country := range countries {
+			for _, key := range keysOf(country) {
+				key = fold(key)
+				if key == "" {
+					continue
+				}
+				if other, ok := level[key]; ok && other.Alpha2 != country.Alpha2 {
+					ambiguous[key] = true
+				}
+				level[key] = country
+			}
+		}
+		for key, country := range level {
+			if _, taken := index[key]; !taken && !ambiguous[key] {
+				index[key] = country
+			}
+		}
+	}
+	return index
+}
+
+// Resolve returns the country identified by input, which may be an alpha-2, alpha-3 or numeric code,
+// the common, official or native name of the country, or a common abbreviation or former name (e.g. "UK"
+// or "Swaziland"). Case, accents, punctuation and surrounding whitespace are ignored, and numeric codes
+// of one to three digits may omit their leading zeros.
+//
+// Parameters:
+// - input: The country code or name, e.g. "no", "NOR", "578", "Norway", "Kingdom of Norway" or "Norge".
+//
+// Returns:
+// - Country: The country, if found.
+// - bool: False if no country (or more than one country) is identified by input.
+func Resolve(input string) (Country, bool) {
+	if code := strings.TrimSpace(input); isNumericCode(code) {
+		country, ok := byKey[strings.Repeat("0", 3-len(code))+code] // Pad numeric codes to three digits
+		return country, ok
+	}
+	key := fold(input)
+	if strings.Trim(key, "0123456789") == "" {
+		return Country{}, false // Digits with signs or punctuation, which folding would drop
+	}
+	country, ok := byKey[key]
+	return country, ok
+}
+
+// isNumericCode reports whether s consists of one to three ASCII digits.
+func isNumericCode(s string) bool {
+	if len(s) == 0 || len(s) > 3 {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// fold normalizes a code or name for matching: it is lower-cased and stripped of accents,
+// apostrophes and periods are dropped, other punctuation is treated as a space, and whitespace
+// is collapsed, so that e.g. "  Côte  d'Ivoire" matches "cote divoire" and "Guinea-Bissau" matches "guinea bissau".
+func fold(s string) string {
+	folded, _, err := transform.String(transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC), s)
+	if err != nil {
+		folded = s
+	}
+	folded = strings.Map(func(r rune) rune {
+		switch {
+		case r == '\'' || r == '’' || r == '.':
+			return -1
+		case unicode.IsPunct(r):
+			return ' '
+		default:
+			return r
+		}
+	}, folded)
+	return strings.Join(strings.Fields(strings.ToLower(folded)), " ")
+}
+
+// Suggest returns up to limit countries the input was likely meant to identify, best match first.
+// The input is compared with the alpha-2 and alpha-3 codes, allowing a single typo,
+// and with the country names, allowing roughly one typo per four letters.
+//
+// Parameters:
+// - input: The unrecognised country code or name.
+// - limit: The maximum number of suggestions.
+//
+// Returns:
+// - []Country: The suggested countries, empty if nothing is close enough.
+//
+// Example:
+//
+//	Suggest("norway", 3) // [{NO NOR 578 Norway}]
+//	Suggest("nx", 3)     // Countries with a code one letter away from NX, e.g. NA, NC, NE
+func Suggest(input string, limit int) []Country {
+	input = fold(input)
+	if input == "" || limit <= 0 {
+		return nil
+	}
+
+	// Candidates are ranked by their score: 0 for an exact code or name, 1 for a name
+	// starting with the input and 1 + the number of typos otherwise
+	type candidate struct {
+		country Country
+		score   int
+	}
+	var candidates []candidate
+	for _, country := range countries {
+		score := -1
+		consider := func(s int) {
+			if score < 0 || s < score {
+				score = s
+			}
+		}
+		considerTypos := func(typos int) {
+			if typos == 0 {
+				consider(0)
+			} else {
+				consider(1 + typos)
+			}
+		}
+
+		name := fold(country.Name)
+		for _, code := range []string{strings.ToLower(country.Alpha2), strings.ToLower(country.Alpha3)} {
+			if d := editDistance(input, code); d <= 1 {
+				considerTypos(d)
+			}
+		}
+		if len(input) >= 3 && strings.HasPrefix(name, input) {
+			consider(1)
+		}
+		if d := editDistance(input, name); d <= len([]rune(name))/4 {
+			considerTypos(d)
+		}
+
+		if score >= 0 {
+			candidates = append(candidates, candidate{country, score})
+		}
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].score < candidates[j].score
+	})
+	if len(candidates) > limit {
+		candidates = candidates[:limit]
+	}
+
+	suggestions := make([]Country, len(candidates))
+	for i, c := range candidates {
+		suggestions[i] = c.country
+	}
+	return suggestions
+}
+
+// editDistance returns the Levenshtein distance between a and b, counted in runes.
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	previous := make([]int, len(rb)+1)
+	current := make([]int, len(rb)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		current[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(rb)]
+}
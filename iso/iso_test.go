@@ -0,0 +1,62 @@
+package iso
+
+import "testing"
+
+func TestResolve(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"no", "NO"},
+		{" NOR ", "NO"},
+		{"578", "NO"},
+		{"20", "AD"},
+		{"Norway", "NO"},
+		{"kingdom of norway", "NO"},
+		{"Norge", "NO"},
+		{"Czechia", "CZ"},
+		{"Czech Republic", "CZ"},
+		{"North Macedonia", "MK"},
+		{"Macedonia", "MK"},
+		{"Eswatini", "SZ"},
+		{"Swaziland", "SZ"},
+		{"UK", "GB"},
+		{"Great Britain", "GB"},
+		{"cote divoire", "CI"},
+		{"Côte d'Ivoire", "CI"},
+		{"guinea bissau", "GW"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			country, ok := Resolve(tt.input)
+			if !ok || country.Alpha2 != tt.want {
+				t.Errorf("expected %s, got %s (%t)", tt.want, country.Alpha2, ok)
+			}
+		})
+	}
+}
+
+func TestResolveRejectsInvalidNumericCodes(t *testing.T) {
+	for _, input := range []string{"+578", "-578", "0578", "5780", "000", "5 78", ""} {
+		if country, ok := Resolve(input); ok {
+			t.Errorf("expected %q to be rejected, got %s", input, country.Alpha2)
+		}
+	}
+}
+
+func TestSuggest(t *testing.T) {
+	suggestions := Suggest("norwy", 3)
+	if len(suggestions) == 0 || suggestions[0].Alpha2 != "NO" {
+		t.Errorf("expected Norway to be suggested first, got %+v", suggestions)
+	}
+	if suggestions := Suggest("zzzzzzzz", 3); len(suggestions) != 0 {
+		t.Errorf("expected no suggestions, got %+v", suggestions)
+	}
+}
+
+func TestSearch(t *testing.T) {
+	matches := Search("eswat", 3)
+	if len(matches) == 0 || matches[0].Country.Alpha2 != "SZ" {
+		t.Errorf("expected Eswatini to be found first, got %+v", matches)
+	}
+}
@@ -0,0 +1,187 @@
+package iso
+
+import (
+	"sort"
+	"strings"
+)
+
+// NameKind identifies which name of a country a search matched.
+type NameKind int
+
+// Name kinds, in order of precedence when ranking search results
+const (
+	NameCode     NameKind = iota // The alpha-2, alpha-3 or numeric code
+	NameCommon                   // The common English name
+	NameOfficial                 // The official English name
+	NameNative                   // A native name
+	NameAlt                      // An alternative spelling
+)
+
+// String returns the name of the kind, as reported by the search endpoint.
+func (k NameKind) String() string {
+	switch k {
+	case NameCode:
+		return "code"
+	case NameCommon:
+		return "common"
+	case NameOfficial:
+		return "official"
+	case NameNative:
+		return "native"
+	default:
+		return "alt"
+	}
+}
+
+// Match quality, best first
+const (
+	matchExact      = iota // The name equals the query
+	matchPrefix            // The name starts with the query
+	matchWordPrefix        // A word of the name starts with the query
+	matchSubstring         // The name contains the query
+	matchFuzzy             // The name starts with the query, allowing roughly one typo per four letters
+)
+
+// Match is a search result.
+type Match struct {
+	Country Country
+	Name    string   // The name (or code) of the country that matched the query
+	Kind    NameKind // Which name of the country matched
+	quality int
+}
+
+// searchEntry is a name of a country in the search index.
+type searchEntry struct {
+	country int // Index into countries
+	name    string
+	folded  string
+	words   []string
+	kind    NameKind
+}
+
+// searchIndex holds every code and name of every country, built once from the embedded table.
+var searchIndex []searchEntry
+
+// buildSearchIndex creates the search index of the countries.
+func buildSearchIndex(countries []Country) []searchEntry {
+	var index []searchEntry
+	add := func(i int, kind NameKind, names ...string) {
+		for _, name := range names {
+			if folded := fold(name); folded != "" {
+				index = append(index, searchEntry{country: i, name: name, folded: folded, words: words(folded), kind: kind})
+			}
+		}
+	}
+	for i, country := range countries {
+		add(i, NameCode, country.Alpha2, country.Alpha3, country.Numeric)
+		add(i, NameCommon, country.Name)
+		add(i, NameOfficial, country.Official)
+		add(i, NameNative, country.Native...)
+		add(i, NameAlt, country.Alt...)
+	}
+	return index
+}
+
+// Search returns up to limit countries matching the query, for type-ahead.
+// Case, accents and surrounding whitespace are ignored.
+//
+// Results are ranked by the quality of the match (exact, prefix, prefix of a word, substring,
+// prefix with typos), then by the name matched (code, common, official, native, alternative spelling),
+// then by length and alphabetically. Codes only match exactly, and every country appears at most once,
+// with the best of its matching names.
+//
+// Parameters:
+// - query: The text typed so far, e.g. "nor".
+// - limit: The maximum number of results.
+//
+// Returns:
+// - []Match: The matching countries, best match first.
+//
+// Example:
+//
+//	Search("nor", 3)   // Norway, Norfolk Island, North Korea
+//	Search("norge", 3) // Norway (native name "Norge")
+func Search(query string, limit int) []Match {
+	query = fold(query)
+	if query == "" || limit <= 0 {
+		return nil
+	}
+
+	best := make(map[int]Match)
+	for _, entry := range searchIndex {
+		quality, ok := matchQuality(query, entry)
+		if !ok {
+			continue
+		}
+		match := Match{Country: countries[entry.country], Name: entry.name, Kind: entry.kind, quality: quality}
+		if current, seen := best[entry.country]; !seen || ranksBefore(match, current) {
+			best[entry.country] = match
+		}
+	}
+
+	matches := make([]Match, 0, len(best))
+	for _, match := range best {
+		matches = append(matches, match)
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		return ranksBefore(matches[i], matches[j])
+	})
+	if len(matches) > limit {
+		matches = matches[:limit]
+	}
+	return matches
+}
+
+// matchQuality returns how well the folded query matches the name of the entry.
+func matchQuality(query string, entry searchEntry) (int, bool) {
+	switch {
+	case entry.folded == query:
+		return matchExact, true
+	case entry.kind == NameCode:
+		return 0, false
+	case strings.HasPrefix(entry.folded, query):
+		return matchPrefix, true
+	}
+	for _, word := range entry.words {
+		if strings.HasPrefix(word, query) {
+			return matchWordPrefix, true
+		}
+	}
+	if strings.Contains(entry.folded, query) {
+		return matchSubstring, true
+	}
+
+	// Compare the query with the starts of the name of about the same length (allowing for a missing
+	// or an extra letter), so that typos are tolerated while typing
+	runes := []rune(query)
+	if len(runes) < 5 {
+		return 0, false
+	}
+	name := []rune(entry.folded)
+	for n := len(runes) - 1; n <= len(runes)+1 && n <= len(name); n++ {
+		if editDistance(query, string(name[:n])) <= len(runes)/4 {
+			return matchFuzzy, true
+		}
+	}
+	return 0, false
+}
+
+// ranksBefore reports whether match a is ranked before match b.
+func ranksBefore(a, b Match) bool {
+	if a.quality != b.quality {
+		return a.quality < b.quality
+	}
+	if a.Kind != b.Kind {
+		return a.Kind < b.Kind
+	}
+	// Prefer the names closest to complete, e.g. "Norway" over "Northern Mariana Islands" for "nor"
+	if la, lb := len([]rune(a.Name)), len([]rune(b.Name)); la != lb {
+		return la < lb
+	}
+	return a.Country.Name < b.Country.Name
+}
+
+// words splits a folded name into its words. Folding already turned punctuation into spaces.
+func words(folded string) []string {
+	return strings.Fields(folded)
+}
@@ -0,0 +1,222 @@
+// Package projection fits growth models to a population history and projects it into the future.
+//
+// Every model is fitted as a straight line through the population history in a transformed space:
+// the population itself for linear growth, its logarithm for exponential growth, and the logit of
+// the population relative to a carrying capacity for logistic growth. This keeps the fitting in-process
+// and lets every model share the same least-squares fit and prediction intervals.
+package projection
+
+import (
+	"errors"
+	"fmt"
+	"github.com/SigurdRiseth/CountryInfoService/utils"
+	"math"
+	"slices"
+)
+
+// Method is a growth model that can be fitted to a population history.
+type Method string
+
+// Growth models
+const (
+	Linear      Method = "linear"      // Constant growth per year
+	Exponential Method = "exponential" // Constant growth rate per year
+	Logistic    Method = "logistic"    // Growth slowing down towards a carrying capacity
+)
+
+// Methods lists every growth model, for error messages.
+var Methods = []Method{Linear, Exponential, Logistic}
+
+// Confidence is the confidence level of the bands returned by Model.Predict.
+const Confidence = 0.95
+
+// zScore is the standard normal quantile of the two-sided Confidence level.
+const zScore = 1.959964
+
+// minPoints is the number of years of data needed to fit a model and estimate its error.
+const minPoints = 3
+
+// Errors returned by Fit
+var (
+	ErrNotEnoughData = fmt.Errorf("at least %d years of population data are needed", minPoints)
+	ErrNonPositive   = errors.New("the population must be positive in every year")
+)
+
+// ParseMethod returns the growth model with the given name, and whether it exists.
+func ParseMethod(name string) (Method, bool) {
+	method := Method(name)
+	return method, slices.Contains(Methods, method)
+}
+
+// Model is a growth model fitted to a population history.
+type Model struct {
+	Method   Method
+	Capacity float64 // The carrying capacity of a logistic model, zero for the other models
+	RSquared float64 // The coefficient of determination of the fit, on the original scale
+	RMSE     float64 // The root-mean-square error of the fit, on the original scale
+
+	origin    int     // The year at t = 0
+	intercept float64 // The intercept of the line in the transformed space
+	slope     float64 // The slope of the line in the transformed space, per year
+	points    int     // The number of years fitted
+	meanT     float64 // The mean of the fitted t values
+	sumSquare float64 // The sum of the squared deviations of the fitted t values from their mean
+	stdErr    float64 // The standard error of the residuals in the transformed space
+}
+
+// Fit fits a growth model to a population history.
+//
+// Parameters:
+//   - values ([]utils.YearValue): The population history, in any order and with any gaps between the years.
+//   - method (Method): The growth model to fit.
+//
+// Returns:
+//   - *Model: The fitted model.
+//   - error: ErrNotEnoughData if there are fewer than 3 years of data, or ErrNonPositive
+//     if an exponential or logistic model is fitted to a zero population.
+//
+// Example Usage:
+//
+//	model, err := projection.Fit(populationData.PopulationCounts, projection.Exponential)
+//	value, lower, upper := model.Predict(2040)
+func Fit(values []utils.YearValue, method Method) (*Model, error) {
+	if len(values) < minPoints {
+		return nil, ErrNotEnoughData
+	}
+	byYear := slices.Clone(values)
+	slices.SortFunc(byYear, func(a, b utils.YearValue) int { return a.Year - b.Year })
+
+	largest := 0.0
+	for _, v := range byYear {
+		if v.Value <= 0 && method != Linear {
+			return nil, ErrNonPositive
+		}
+		largest = max(largest, float64(v.Value))
+	}
+
+	if method != Logistic {
+		return fitLine(byYear, method, 0), nil
+	}
+
+	// The capacity of a logistic model cannot be fitted by least squares directly, so the capacity
+	// with the smallest error is searched for among a range of capacities above the largest population,
+	// first on a coarse logarithmic grid and then by golden-section search around the best grid point.
+	const gridPoints = 200
+	lower, upper := math.Log(largest*1.0001), math.Log(largest*20)
+	step := (upper - lower) / gridPoints
+	best := 0
+	var bestModel *Model
+	for i := 0; i <= gridPoints; i++ {
+		model := fitLine(byYear, Logistic, math.Exp(lower+float64(i)*step))
+		if bestModel == nil || model.RMSE < bestModel.RMSE {
+			best, bestModel = i, model
+		}
+	}
+
+	a, b := lower+float64(max(best-1, 0))*step, lower+float64(min(best+1, gridPoints))*step
+	ratio := (math.Sqrt(5) - 1) / 2
+	for range 50 {
+		c, d := b-ratio*(b-a), a+ratio*(b-a)
+		if fitLine(byYear, Logistic, math.Exp(c)).RMSE < fitLine(byYear, Logistic, math.Exp(d)).RMSE {
+			b = d
+		} else {
+			a = c
+		}
+	}
+	if model := fitLine(byYear, Logistic, math.Exp((a+b)/2)); model.RMSE < bestModel.RMSE {
+		bestModel = model
+	}
+	return bestModel, nil
+}
+
+// fitLine fits a straight line through the values sorted by year in the transformed space of the method,
+// by ordinary least squares.
+func fitLine(values []utils.YearValue, method Method, capacity float64) *Model {
+	model := &Model{Method: method, Capacity: capacity, origin: values[0].Year, points: len(values)}
+
+	var sumT, sumZ float64
+	for _, v := range values {
+		sumT += float64(v.Year - model.origin)
+		sumZ += model.transform(float64(v.Value))
+	}
+	n := float64(len(values))
+	model.meanT = sumT / n
+	meanZ := sumZ / n
+
+	var sumTZ float64
+	for _, v := range values {
+		t := float64(v.Year-model.origin) - model.meanT
+		model.sumSquare += t * t
+		sumTZ += t * (model.transform(float64(v.Value)) - meanZ)
+	}
+	model.slope = sumTZ / model.sumSquare
+	model.intercept = meanZ - model.slope*model.meanT
+
+	// Errors in the transformed space for the prediction intervals, and on the original scale for the fit quality
+	var residuals, squaredErrors, squaredDeviations, sumValues float64
+	for _, v := range values {
+		sumValues += float64(v.Value)
+	}
+	meanValue := sumValues / n
+	for _, v := range values {
+		t := float64(v.Year - model.origin)
+		residual := model.transform(float64(v.Value)) - (model.intercept + model.slope*t)
+		residuals += residual * residual
+		fitted := model.inverse(model.intercept + model.slope*t)
+		squaredErrors += (float64(v.Value) - fitted) * (float64(v.Value) - fitted)
+		squaredDeviations += (float64(v.Value) - meanValue) * (float64(v.Value) - meanValue)
+	}
+	model.stdErr = math.Sqrt(residuals / (n - 2))
+	model.RMSE = math.Sqrt(squaredErrors / n)
+	switch {
+	case squaredDeviations > 0:
+		model.RSquared = 1 - squaredErrors/squaredDeviations
+	case squaredErrors == 0:
+		model.RSquared = 1 // A constant population fitted exactly
+	}
+	return model
+}
+
+// Predict projects the population in the given year.
+//
+// Returns:
+//   - value (float64): The projected population.
+//   - lower, upper (float64): The bounds of the prediction interval at the Confidence level,
+//     assuming normally distributed residuals in the transformed space of the model.
+//     As consecutive years of a population history are correlated, the bands are best read as a lower bound on the uncertainty.
+func (m *Model) Predict(year int) (value, lower, upper float64) {
+	t := float64(year - m.origin)
+	z := m.intercept + m.slope*t
+	margin := zScore * m.stdErr * math.Sqrt(1+1/float64(m.points)+(t-m.meanT)*(t-m.meanT)/m.sumSquare)
+
+	value = m.inverse(z)
+	lower, upper = m.inverse(z-margin), m.inverse(z+margin)
+	if lower > upper {
+		lower, upper = upper, lower // The logistic transform is decreasing
+	}
+	return max(value, 0), max(lower, 0), max(upper, 0)
+}
+
+// transform maps a population into the space where the model is a straight line.
+func (m *Model) transform(y float64) float64 {
+	switch m.Method {
+	case Exponential:
+		return math.Log(y)
+	case Logistic:
+		return math.Log(m.Capacity/y - 1)
+	default:
+		return y
+	}
+}
+
+// inverse maps a value of the transformed space back to a population.
+func (m *Model) inverse(z float64) float64 {
+	switch m.Method {
+	case Exponential:
+		return math.Exp(z)
+	case Logistic:
+		return m.Capacity / (1 + math.Exp(z))
+	default:
+		return z
+	}
+}
@@ -0,0 +1,93 @@
+package projection
+
+import (
+	"errors"
+	"github.com/SigurdRiseth/CountryInfoService/utils"
+	"math"
+	"testing"
+)
+
+// history returns the population of the years from 2000 to 2000+n-1, as given by f of the number of years since 2000.
+func history(n int, f func(t float64) float64) []utils.YearValue {
+	values := make([]utils.YearValue, n)
+	for i := range values {
+		values[i] = utils.YearValue{Year: 2000 + i, Value: int(math.Round(f(float64(i))))}
+	}
+	return values
+}
+
+// assertClose fails the test unless got is within a relative tolerance of want.
+func assertClose(t *testing.T, name string, got, want, tolerance float64) {
+	t.Helper()
+	if math.Abs(got-want) > tolerance*math.Abs(want) {
+		t.Errorf("expected %s %v, got %v", name, want, got)
+	}
+}
+
+func TestFitLinear(t *testing.T) {
+	model, err := Fit(history(20, func(t float64) float64 { return 1000 + 50*t }), Linear)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	value, lower, upper := model.Predict(2030)
+	assertClose(t, "projection", value, 2500, 1e-9)
+	assertClose(t, "R²", model.RSquared, 1, 1e-9)
+	if lower > value || upper < value {
+		t.Errorf("expected the projection within its band, got %v not in [%v, %v]", value, lower, upper)
+	}
+}
+
+func TestFitExponential(t *testing.T) {
+	model, err := Fit(history(30, func(t float64) float64 { return 1e6 * math.Pow(1.02, t) }), Exponential)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	value, _, _ := model.Predict(2040)
+	assertClose(t, "projection", value, 1e6*math.Pow(1.02, 40), 1e-4)
+	assertClose(t, "R²", model.RSquared, 1, 1e-6)
+}
+
+func TestFitLogistic(t *testing.T) {
+	const capacity = 5e6
+	logistic := func(t float64) float64 { return capacity / (1 + 9*math.Exp(-0.1*t)) }
+	model, err := Fit(history(60, logistic), Logistic)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertClose(t, "capacity", model.Capacity, capacity, 0.01)
+	value, _, _ := model.Predict(2100)
+	assertClose(t, "projection", value, logistic(100), 0.01)
+	if value >= model.Capacity {
+		t.Errorf("expected the projection below the capacity %v, got %v", model.Capacity, value)
+	}
+}
+
+func TestFitErrors(t *testing.T) {
+	if _, err := Fit(history(2, func(t float64) float64 { return 100 }), Linear); !errors.Is(err, ErrNotEnoughData) {
+		t.Errorf("expected ErrNotEnoughData, got %v", err)
+	}
+
+	withZero := history(5, func(t float64) float64 { return 100 * t })
+	if _, err := Fit(withZero, Linear); err != nil {
+		t.Errorf("expected a linear model to fit a zero population, got %v", err)
+	}
+	for _, method := range []Method{Exponential, Logistic} {
+		if _, err := Fit(withZero, method); !errors.Is(err, ErrNonPositive) {
+			t.Errorf("expected ErrNonPositive for the %s model, got %v", method, err)
+		}
+	}
+}
+
+func TestParseMethod(t *testing.T) {
+	for _, method := range Methods {
+		if parsed, ok := ParseMethod(string(method)); !ok || parsed != method {
+			t.Errorf("expected %s to be parsed, got %s (%t)", method, parsed, ok)
+		}
+	}
+	if _, ok := ParseMethod("quadratic"); ok {
+		t.Error("expected an unknown method to be rejected")
+	}
+}
@@ -1,11 +1,18 @@
 package server
 
 import (
+	"context"
+	"encoding/json"
+	"github.com/SigurdRiseth/CountryInfoService/cache"
+	"github.com/SigurdRiseth/CountryInfoService/clients"
 	"github.com/SigurdRiseth/CountryInfoService/config"
 	"github.com/SigurdRiseth/CountryInfoService/handlers"
 	"github.com/SigurdRiseth/CountryInfoService/utils"
 	"log"
+	"mime"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -13,6 +20,9 @@ import (
 func StartServer() {
 	handler.StartTime = time.Now() // Initialize start time
 
+	// Inject the upstream API clients into the handlers
+	setupClients()
+
 	// Get the port from environment variables, default to 8080
 	port := config.GetPort()
 
@@ -24,17 +34,68 @@ func StartServer() {
 	log.Fatal(http.ListenAndServe(":"+port, router))
 }
 
+// setupClients creates the upstream API clients and injects them into the handlers.
+// Every upstream API gets its own timeout, retry policy and circuit breaker, configured from the environment.
+// Unless disabled by a zero $CACHE_TTL, the clients are wrapped by a shared response cache,
+// which is persisted to $CACHE_DIR if set.
+func setupClients() {
+	restCountriesUpstream := newUpstream("RestCountries", "RESTCOUNTRIES")
+	countriesNowUpstream := newUpstream("CountriesNow", "COUNTRIESNOW")
+	handler.RestCountriesUpstream, handler.CountriesNowUpstream = restCountriesUpstream, countriesNowUpstream
+	handler.Breakers = []*clients.Breaker{restCountriesUpstream.Breaker(), countriesNowUpstream.Breaker()}
+
+	var restCountries clients.RestCountriesClient = clients.NewRestCountriesHTTP(utils.RestCountriesApiUrl, restCountriesUpstream)
+	var countriesNow clients.CountriesNowClient = clients.NewCountriesNowHTTP(utils.CountriesNowApiUrl, countriesNowUpstream)
+
+	if ttl := config.GetCacheTTL(); ttl > 0 {
+		responseCache := cache.New(ttl, config.GetCacheMaxSize())
+		responseCache.SetStaleWhileRevalidate(config.GetCacheStaleWhileRevalidate())
+		if dir := config.GetCacheDir(); dir != "" {
+			store, err := cache.NewDiskStore(dir)
+			if err != nil {
+				log.Printf("Error opening persistent cache, caching in memory only: %v", err)
+			} else {
+				responseCache.SetStore(store)
+				log.Printf("Persisting cached upstream responses to %s", dir)
+			}
+		}
+		restCountries = clients.NewCachedRestCountries(restCountries, responseCache)
+		countriesNow = clients.NewCachedCountriesNow(countriesNow, responseCache)
+		handler.Cache = responseCache
+		log.Printf("Caching upstream responses for %s", ttl)
+	}
+
+	handler.RestCountries = restCountries
+	handler.CountriesNow = countriesNow
+}
+
+// newUpstream creates the shared HTTP client of the upstream API called name, configured
+// by the environment variables starting with prefix.
+func newUpstream(name, prefix string) *clients.Upstream {
+	breaker := clients.NewBreaker(name, config.GetBreakerSettings(prefix))
+	return clients.NewUpstream(name, config.GetRetryPolicy(prefix), breaker, nil)
+}
+
 // setupRouter initializes the HTTP request multiplexer (router) and defines the endpoints.
 //
 // Returns:
 // - *http.ServeMux: A pointer to the initialized HTTP request multiplexer.
 func setupRouter() *http.ServeMux {
 	router := http.NewServeMux()
+	timeout := config.GetRequestTimeout()
 
 	// Define the endpoints
-	router.HandleFunc(utils.GetInfoPath(""), makeHTTPHandleFunc(handler.HandleInfo))
-	router.HandleFunc(utils.GetPopulationPath(""), handler.HandlePopulation)
-	router.HandleFunc(utils.GetStatusPath(), makeHTTPHandleFunc(handler.HandleStatus))
+	router.HandleFunc(utils.GetInfoPath(""), withDeadline(timeout, makeHTTPHandleFunc(handler.HandleInfo)))
+	router.HandleFunc(utils.GetInfoV2Path(""), withDeadline(timeout, makeHTTPHandleFunc(handler.HandleInfoV2)))
+	router.HandleFunc(utils.GetPopulationPath(""), withDeadline(timeout, makeHTTPHandleFunc(handler.HandlePopulation)))
+	router.HandleFunc(utils.GetProjectionPath(), withDeadline(timeout, makeHTTPHandleFunc(handler.HandleProjection)))
+	router.HandleFunc(utils.GetComparePath(), withDeadline(timeout, makeHTTPHandleFunc(handler.HandleCompare)))
+	router.HandleFunc(utils.GetStatusPath(), withDeadline(timeout, makeHTTPHandleFunc(handler.HandleStatus)))
+	router.HandleFunc(utils.GetCitiesPath(""), withDeadline(timeout, makeHTTPHandleFunc(handler.HandleCities)))
+	router.HandleFunc(utils.GetStatesPath(), withDeadline(timeout, makeHTTPHandleFunc(handler.HandleStates)))
+	router.HandleFunc(utils.GetStateCitiesPath(), withDeadline(timeout, makeHTTPHandleFunc(handler.HandleStateCities)))
+	router.HandleFunc(utils.GetSearchPath(), makeHTTPHandleFunc(handler.HandleSearch))
+	router.HandleFunc(utils.GetProblemPath(), makeHTTPHandleFunc(handler.HandleProblem))
 	router.HandleFunc("/", handler.DefaultHandler)
 
 	return router
@@ -43,8 +104,9 @@ func setupRouter() *http.ServeMux {
 type apiFunc func(w http.ResponseWriter, r *http.Request) error
 
 // makeHTTPHandleFunc is a helper function that wraps an apiFunc with error handling.
-// It returns an http.HandlerFunc that logs the error and sends an HTTP 500 status code
-// if the wrapped function returns an error.
+// It returns an http.HandlerFunc that logs the error returned by the wrapped function and,
+// unless the wrapped function already started its response, sends an error response with
+// the status code matching the type of the error (see utils.ErrorKind).
 //
 // Parameters:
 // - f: The apiFunc to be wrapped.
@@ -53,9 +115,119 @@ type apiFunc func(w http.ResponseWriter, r *http.Request) error
 // - http.HandlerFunc: A function that handles HTTP requests and responses.
 func makeHTTPHandleFunc(f apiFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		if err := f(w, r); err != nil {
+		recorder := &responseRecorder{ResponseWriter: w}
+		if err := f(recorder, r); err != nil {
 			log.Printf("Error: %v", err)
-			// TODO: switch on error type and return appropriate status code
+			if recorder.wroteHeader {
+				return // Too late to send an error response
+			}
+			writeError(w, r, err)
+		}
+	}
+}
+
+// writeError sends the error response for err: the status code and machine-readable code
+// are derived from its utils.ErrorKind, and the message is the client-facing message of the error.
+// Clients accepting application/problem+json get an RFC 7807 problem details document,
+// all other clients get the standard utils.APIResponse.
+//
+// Parameters:
+// - w: The response writer.
+// - r: The request that failed.
+// - err: The error returned by a handler.
+func writeError(w http.ResponseWriter, r *http.Request, err error) {
+	apiErr := utils.AsAPIError(err)
+	status := apiErr.Kind.StatusCode()
+
+	var body interface{}
+	if acceptsProblemJSON(r) {
+		w.Header().Set("Content-Type", utils.ProblemContentType)
+		body = utils.ProblemDetails{
+			Type:     apiErr.Kind.ProblemType(),
+			Title:    http.StatusText(status),
+			Status:   status,
+			Detail:   apiErr.Message,
+			Instance: r.URL.RequestURI(),
+			Code:     apiErr.Kind.Code(),
+			Upstream: apiErr.Upstream,
+
+			Suggestions: apiErr.Suggestions,
+		}
+	} else {
+		w.Header().Set("Content-Type", "application/json")
+		body = utils.APIResponse{
+			Error:   true,
+			Message: apiErr.Message,
+			Code:    apiErr.Kind.Code(),
+			Data:    nil,
+
+			Suggestions: apiErr.Suggestions,
+		}
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Add("Vary", "Accept")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		log.Printf("Error encoding error response: %v", err)
+	}
+}
+
+// acceptsProblemJSON reports whether the Accept header of r explicitly lists application/problem+json
+// with a non-zero quality. Wildcards do not count, so the standard error format stays the default.
+//
+// Parameters:
+// - r: The request to check.
+//
+// Returns:
+// - bool: True if the client asked for RFC 7807 problem details.
+func acceptsProblemJSON(r *http.Request) bool {
+	for _, accept := range r.Header.Values("Accept") {
+		for _, mediaRange := range strings.Split(accept, ",") {
+			mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(mediaRange))
+			if err != nil || mediaType != utils.ProblemContentType {
+				continue
+			}
+			if q, err := strconv.ParseFloat(params["q"], 64); err == nil && q <= 0 {
+				continue
+			}
+			return true
 		}
 	}
+	return false
+}
+
+// responseRecorder is an http.ResponseWriter remembering whether the response has been started.
+type responseRecorder struct {
+	http.ResponseWriter
+	wroteHeader bool
+}
+
+// WriteHeader records that the response has been started and sends the status code.
+func (r *responseRecorder) WriteHeader(statusCode int) {
+	r.wroteHeader = true
+	r.ResponseWriter.WriteHeader(statusCode)
+}
+
+// Write records that the response has been started and writes the body.
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	r.wroteHeader = true
+	return r.ResponseWriter.Write(b)
+}
+
+// withDeadline wraps a handler so that its request context, and with it every upstream call
+// made while serving the request, is cancelled once timeout has passed.
+//
+// Parameters:
+// - timeout: The overall deadline of a request.
+// - next: The handler to wrap.
+//
+// Returns:
+// - http.HandlerFunc: A function that serves the request with the deadline applied.
+func withDeadline(timeout time.Duration, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), timeout)
+		defer cancel()
+		next(w, r.WithContext(ctx))
+	}
 }
@@ -0,0 +1,217 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/SigurdRiseth/CountryInfoService/clients"
+	"github.com/SigurdRiseth/CountryInfoService/handlers"
+	"github.com/SigurdRiseth/CountryInfoService/utils"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestProblemTypesResolve(t *testing.T) {
+	router := setupRouter()
+
+	for _, kind := range utils.ProblemKinds {
+		t.Run(kind.Code(), func(t *testing.T) {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, kind.ProblemType(), nil))
+			if w.Code != http.StatusOK {
+				t.Fatalf("expected status %d, got %d", http.StatusOK, w.Code)
+			}
+
+			var response struct {
+				Data utils.ProblemType `json:"data"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+				t.Fatal(err)
+			}
+			if response.Data.Type != kind.ProblemType() || response.Data.Status != kind.StatusCode() {
+				t.Errorf("unexpected documentation %+v", response.Data)
+			}
+		})
+	}
+}
+
+func TestUnknownProblemType(t *testing.T) {
+	w := httptest.NewRecorder()
+	setupRouter().ServeHTTP(w, httptest.NewRequest(http.MethodGet, utils.ProblemTypeBase+"unknown", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected status %d, got %d", http.StatusNotFound, w.Code)
+	}
+}
+
+// useFakeCountriesNow injects a fake CountriesNow client into the handlers for the duration of the test.
+func useFakeCountriesNow(t *testing.T) *clients.FakeCountriesNow {
+	t.Helper()
+
+	fake := clients.NewFakeCountriesNow()
+	fake.Population["NOR"] = clients.PopulationData{Iso3: "NOR", PopulationCounts: []utils.YearValue{{Year: 2018, Value: 5311916}}}
+	previous := handler.CountriesNow
+	handler.CountriesNow = fake
+	t.Cleanup(func() { handler.CountriesNow = previous })
+	return fake
+}
+
+func TestRequestDeadline(t *testing.T) {
+	countriesNow := useFakeCountriesNow(t)
+	countriesNow.Delay = time.Second
+
+	r := httptest.NewRequest(http.MethodGet, "/countryinfo/v1/population/no", nil)
+	r.SetPathValue("country", "no")
+	w := httptest.NewRecorder()
+	start := time.Now()
+	withDeadline(20*time.Millisecond, makeHTTPHandleFunc(handler.HandlePopulation))(w, r)
+
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("expected the request to be cut off at its deadline, took %s", elapsed)
+	}
+	if w.Code != http.StatusGatewayTimeout {
+		t.Fatalf("expected status %d, got %d", http.StatusGatewayTimeout, w.Code)
+	}
+	var response utils.APIResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatal(err)
+	}
+	if !response.Error || response.Code != utils.KindTimeout.Code() || response.Data != nil {
+		t.Errorf("unexpected response %+v", response)
+	}
+}
+
+func TestMakeHTTPHandleFuncMapsErrorKinds(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    error
+		status int
+		code   string
+	}{
+		{"invalid input", utils.NewInvalidInputError("invalid limit", nil), http.StatusBadRequest, "invalid_input"},
+		{"not found", utils.NewNotFoundError("country xx not found", nil), http.StatusNotFound, "not_found"},
+		{"unprocessable", utils.NewUnprocessableError("cannot fit", nil), http.StatusUnprocessableEntity, "unprocessable"},
+		{"bad response", utils.NewUpstreamBadResponseError(utils.SourceRestCountries, "unexpected response", nil), http.StatusBadGateway, "upstream_bad_response"},
+		{"unavailable", utils.NewUpstreamUnavailableError(utils.SourceCountriesNow, "upstream API is unavailable", nil), http.StatusServiceUnavailable, "upstream_unavailable"},
+		{"timeout", utils.NewTimeoutError(utils.SourceCountriesNow, "timed out", nil), http.StatusGatewayTimeout, "timeout"},
+		{"wrapped deadline", fmt.Errorf("fetching cities: %w", context.DeadlineExceeded), http.StatusGatewayTimeout, "timeout"},
+		{"untyped", errors.New("boom"), http.StatusInternalServerError, "internal_error"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			makeHTTPHandleFunc(func(http.ResponseWriter, *http.Request) error { return tt.err })(w, httptest.NewRequest(http.MethodGet, "/", nil))
+
+			if w.Code != tt.status {
+				t.Errorf("expected status %d, got %d", tt.status, w.Code)
+			}
+			if contentType := w.Header().Get("Content-Type"); contentType != "application/json" {
+				t.Errorf("expected a JSON response, got %q", contentType)
+			}
+			var response utils.APIResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+				t.Fatal(err)
+			}
+			if !response.Error || response.Code != tt.code || response.Message != utils.AsAPIError(tt.err).Message {
+				t.Errorf("unexpected response %+v", response)
+			}
+		})
+	}
+}
+
+func TestMakeHTTPHandleFuncAfterResponseStarted(t *testing.T) {
+	w := httptest.NewRecorder()
+	makeHTTPHandleFunc(func(w http.ResponseWriter, r *http.Request) error {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"error":false`))
+		return errors.New("encoding failed halfway")
+	})(w, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if w.Code != http.StatusOK || w.Body.String() != `{"error":false` {
+		t.Errorf("expected the started response to be left alone, got %d %q", w.Code, w.Body)
+	}
+}
+
+func TestAcceptsProblemJSON(t *testing.T) {
+	tests := []struct {
+		accept []string
+		want   bool
+	}{
+		{nil, false},
+		{[]string{"application/json"}, false},
+		{[]string{"application/problem+json"}, true},
+		{[]string{"application/json, application/problem+json;q=0.5"}, true},
+		{[]string{"application/json", "application/problem+json"}, true},
+		{[]string{"application/problem+json;q=0"}, false},
+		{[]string{"application/problem+json; q=0.0"}, false},
+		{[]string{"*/*"}, false},
+		{[]string{"application/*"}, false},
+		{[]string{"not a media type"}, false},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		for _, accept := range tt.accept {
+			r.Header.Add("Accept", accept)
+		}
+		if got := acceptsProblemJSON(r); got != tt.want {
+			t.Errorf("Accept %q: expected %t, got %t", tt.accept, tt.want, got)
+		}
+	}
+}
+
+func TestWriteErrorProblemDetails(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/countryinfo/v1/population/no?limit=2000-2010", nil)
+	r.Header.Set("Accept", "application/problem+json")
+	w := httptest.NewRecorder()
+	writeError(w, r, utils.NewUpstreamUnavailableError(utils.SourceCountriesNow, "upstream API is unavailable", errors.New("status 503")))
+
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected status %d, got %d", http.StatusServiceUnavailable, w.Code)
+	}
+	if contentType := w.Header().Get("Content-Type"); contentType != utils.ProblemContentType {
+		t.Errorf("expected content type %s, got %q", utils.ProblemContentType, contentType)
+	}
+	if vary := w.Header().Get("Vary"); vary != "Accept" {
+		t.Errorf("expected the response to vary by Accept, got %q", vary)
+	}
+
+	var problem utils.ProblemDetails
+	if err := json.Unmarshal(w.Body.Bytes(), &problem); err != nil {
+		t.Fatal(err)
+	}
+	want := utils.ProblemDetails{
+		Type:     utils.ProblemTypeBase + "upstream_unavailable",
+		Title:    "Service Unavailable",
+		Status:   http.StatusServiceUnavailable,
+		Detail:   "upstream API is unavailable",
+		Instance: "/countryinfo/v1/population/no?limit=2000-2010",
+		Code:     "upstream_unavailable",
+		Upstream: utils.SourceCountriesNow,
+	}
+	if problem.Type != want.Type || problem.Title != want.Title || problem.Status != want.Status || problem.Detail != want.Detail ||
+		problem.Instance != want.Instance || problem.Code != want.Code || problem.Upstream != want.Upstream {
+		t.Errorf("expected %+v, got %+v", want, problem)
+	}
+}
+
+func TestWriteErrorProblemDetailsOmitsEmptyMembers(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/countryinfo/v1/info/xx", nil)
+	r.Header.Set("Accept", "application/problem+json")
+	w := httptest.NewRecorder()
+	writeError(w, r, errors.New("boom"))
+
+	var members map[string]any
+	if err := json.Unmarshal(w.Body.Bytes(), &members); err != nil {
+		t.Fatal(err)
+	}
+	if members["type"] != "about:blank" || members["status"] != float64(http.StatusInternalServerError) {
+		t.Errorf("expected an about:blank internal error, got %v", members)
+	}
+	for _, member := range []string{"upstream", "suggestions"} {
+		if _, ok := members[member]; ok {
+			t.Errorf("expected %s to be omitted, got %v", member, members)
+		}
+	}
+}
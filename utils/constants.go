@@ -2,40 +2,103 @@ package utils
 
 // API information
 const (
-	ApiVersion       = "1.0"
-	DefaultCityLimit = 3
+	ApiVersion             = "1.0"
+	DefaultCityLimit       = 3
+	MaxSuggestions         = 5 // Maximum number of countries suggested for an invalid country code
+	DefaultSearchLimit     = 10
+	MaxSearchLimit         = 50
+	DefaultCityPageSize    = 50
+	MaxCityPageSize        = 500
+	DefaultProjectionYears = 10  // Number of years projected past the last year of data by default
+	MaxProjectionYears     = 100 // Maximum number of years projected past the last year of data
+	MaxCompareCountries    = 10  // Maximum number of countries compared at once
+	MaxFillYears           = 10  // Maximum number of years filled in before the first or after the last year of data
 )
 
 // Endpoint paths
 const (
-	BasePath       = "/countryinfo/v1"
-	InfoPath       = "/info/{two_letter_country_code}"
-	PopulationPath = "/population/{two_letter_country_code}"
-	StatusPath     = "/status"
+	BasePath        = "/countryinfo/v1"
+	BasePathV2      = "/countryinfo/v2" // Endpoints whose response shape changed in an incompatible way
+	InfoPath        = "/info/{country}"
+	PopulationPath  = "/population/{country}"
+	ProjectionPath  = "/population/{country}/projection"
+	ComparePath     = "/population/compare"
+	StatusPath      = "/status"
+	SearchPath      = "/search"
+	CitiesPath      = "/cities/{country}"
+	StatesPath      = "/states/{country}"
+	StateCitiesPath = "/states/{country}/{state}/cities"
+	ProblemPath     = "/problems/{code}"
+)
+
+// Error response media types
+const (
+	ProblemContentType = "application/problem+json"
+	ProblemTypeBase    = BasePath + "/problems/" // Prefix of the problem type URIs, followed by ErrorKind.Code
+)
+
+// Upstream API names, as used in warnings
+const (
+	SourceCountriesNow  = "countriesnow"
+	SourceRestCountries = "restcountries"
 )
 
 // Countries-Now API
 const (
-	CountriesNowApiUrl             = "http://129.241.150.113:3500/api/v0.1/"
-	CountriesNowPopulationEndpoint = "countries/population"
-	CountriesNowCityEndpoint       = "countries/cities"
+	CountriesNowApiUrl              = "http://129.241.150.113:3500/api/v0.1/"
+	CountriesNowPopulationEndpoint  = "countries/population"
+	CountriesNowCityEndpoint        = "countries/cities"
+	CountriesNowStatesEndpoint      = "countries/states"
+	CountriesNowStateCitiesEndpoint = "countries/state/cities"
 )
 
 // RestCountries API
 const (
-	RestCountriesApiUrl     = "http://129.241.150.113:8080/v3.1/alpha/"
-	RestCountriesFilter     = "?fields=name,continents,population,languages,borders,flag,capital"
-	RestCountriesIso3Filter = "?fields=cca3"
+	RestCountriesApiUrl = "http://129.241.150.113:8080/v3.1/alpha/"
+	RestCountriesFilter = "?fields=name,continents,population,languages,borders,flag,capital," +
+		"currencies,area,latlng,timezones,idd,tld,subregion,unMember"
 )
 
 func GetInfoPath(countryCode string) string {
 	return BasePath + InfoPath + countryCode
 }
 
+func GetInfoV2Path(countryCode string) string {
+	return BasePathV2 + InfoPath + countryCode
+}
+
 func GetPopulationPath(countryCode string) string {
 	return BasePath + PopulationPath + countryCode
 }
 
+func GetProjectionPath() string {
+	return BasePath + ProjectionPath
+}
+
+func GetComparePath() string {
+	return BasePath + ComparePath
+}
+
 func GetStatusPath() string {
 	return BasePath + StatusPath
 }
+
+func GetCitiesPath(countryCode string) string {
+	return BasePath + CitiesPath + countryCode
+}
+
+func GetStatesPath() string {
+	return BasePath + StatesPath
+}
+
+func GetStateCitiesPath() string {
+	return BasePath + StateCitiesPath
+}
+
+func GetSearchPath() string {
+	return BasePath + SearchPath
+}
+
+func GetProblemPath() string {
+	return BasePath + ProblemPath
+}
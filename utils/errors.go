@@ -0,0 +1,179 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"net/http"
+)
+
+// ErrorKind classifies an error, determining the HTTP status code and machine-readable code of the error response.
+type ErrorKind int
+
+// Error kinds
+const (
+	KindInternal            ErrorKind = iota // Unexpected failure within the service
+	KindInvalidInput                         // The request parameters are invalid
+	KindNotFound                             // The requested country does not exist
+	KindUpstreamUnavailable                  // An upstream API cannot be reached or is failing
+	KindUpstreamBadResponse                  // An upstream API answered with an unexpected or undecodable response
+	KindTimeout                              // An upstream API did not answer in time
+	KindUnprocessable                        // The request is valid, but the data of the country cannot be processed as requested
+)
+
+// StatusCode returns the HTTP status code reported for errors of this kind.
+func (k ErrorKind) StatusCode() int {
+	switch k {
+	case KindInvalidInput:
+		return http.StatusBadRequest
+	case KindNotFound:
+		return http.StatusNotFound
+	case KindUnprocessable:
+		return http.StatusUnprocessableEntity
+	case KindUpstreamUnavailable:
+		return http.StatusServiceUnavailable
+	case KindUpstreamBadResponse:
+		return http.StatusBadGateway
+	case KindTimeout:
+		return http.StatusGatewayTimeout
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// Code returns the machine-readable code reported for errors of this kind.
+func (k ErrorKind) Code() string {
+	switch k {
+	case KindInvalidInput:
+		return "invalid_input"
+	case KindNotFound:
+		return "not_found"
+	case KindUnprocessable:
+		return "unprocessable"
+	case KindUpstreamUnavailable:
+		return "upstream_unavailable"
+	case KindUpstreamBadResponse:
+		return "upstream_bad_response"
+	case KindTimeout:
+		return "timeout"
+	default:
+		return "internal_error"
+	}
+}
+
+// ProblemType returns the RFC 7807 problem type URI reported for errors of this kind.
+// Internal errors carry no further semantics than their status code, and are reported as "about:blank".
+func (k ErrorKind) ProblemType() string {
+	if k == KindInternal {
+		return "about:blank"
+	}
+	return ProblemTypeBase + k.Code()
+}
+
+// Description returns a human-readable explanation of errors of this kind, served as the
+// documentation of its problem type URI.
+func (k ErrorKind) Description() string {
+	switch k {
+	case KindInvalidInput:
+		return "The country code or a query parameter of the request is invalid. " +
+			"The message names the offending parameter, and unknown countries are answered with suggestions."
+	case KindNotFound:
+		return "No data exists for the requested country, e.g. a country without population data."
+	case KindUnprocessable:
+		return "The request is valid, but the data of the country cannot be processed as requested, " +
+			"e.g. an exponential projection of a population history containing a zero population."
+	case KindUpstreamUnavailable:
+		return "An external API cannot be reached, is failing, or its circuit breaker is open. " +
+			"The request may be retried later."
+	case KindUpstreamBadResponse:
+		return "An external API answered with an unexpected or undecodable response."
+	case KindTimeout:
+		return "The external APIs did not answer before the deadline of the request. The request may be retried."
+	default:
+		return "An unexpected failure occurred within the service."
+	}
+}
+
+// ProblemKinds lists the error kinds reported with a problem type URI of their own.
+var ProblemKinds = []ErrorKind{
+	KindInvalidInput,
+	KindNotFound,
+	KindUnprocessable,
+	KindUpstreamUnavailable,
+	KindUpstreamBadResponse,
+	KindTimeout,
+}
+
+// ParseProblemCode returns the error kind with the machine-readable code, if it has a problem type URI of its own.
+func ParseProblemCode(code string) (ErrorKind, bool) {
+	for _, kind := range ProblemKinds {
+		if kind.Code() == code {
+			return kind, true
+		}
+	}
+	return KindInternal, false
+}
+
+// APIError is an error carrying the information needed to build an error response.
+type APIError struct {
+	Kind        ErrorKind
+	Message     string       // Human-readable message returned to the client
+	Upstream    string       // The upstream API involved, if any (see SourceRestCountries and SourceCountriesNow)
+	Suggestions []Suggestion // Countries the client may have meant, for invalid country codes
+	Err         error        // The underlying cause, logged but not returned to the client
+}
+
+// Error returns the message, followed by the underlying cause if any.
+func (e *APIError) Error() string {
+	if e.Err != nil {
+		return e.Message + ": " + e.Err.Error()
+	}
+	return e.Message
+}
+
+// Unwrap returns the underlying cause.
+func (e *APIError) Unwrap() error {
+	return e.Err
+}
+
+// NewInvalidInputError creates an error for invalid request parameters.
+func NewInvalidInputError(message string, err error) *APIError {
+	return &APIError{Kind: KindInvalidInput, Message: message, Err: err}
+}
+
+// NewNotFoundError creates an error for a country that does not exist.
+func NewNotFoundError(message string, err error) *APIError {
+	return &APIError{Kind: KindNotFound, Message: message, Err: err}
+}
+
+// NewUnprocessableError creates an error for data of a country that cannot be processed as requested.
+func NewUnprocessableError(message string, err error) *APIError {
+	return &APIError{Kind: KindUnprocessable, Message: message, Err: err}
+}
+
+// NewUpstreamUnavailableError creates an error for an upstream API that cannot be reached or is failing.
+func NewUpstreamUnavailableError(upstream, message string, err error) *APIError {
+	return &APIError{Kind: KindUpstreamUnavailable, Message: message, Upstream: upstream, Err: err}
+}
+
+// NewUpstreamBadResponseError creates an error for an unexpected or undecodable upstream response.
+func NewUpstreamBadResponseError(upstream, message string, err error) *APIError {
+	return &APIError{Kind: KindUpstreamBadResponse, Message: message, Upstream: upstream, Err: err}
+}
+
+// NewTimeoutError creates an error for an upstream API not answering in time.
+func NewTimeoutError(upstream, message string, err error) *APIError {
+	return &APIError{Kind: KindTimeout, Message: message, Upstream: upstream, Err: err}
+}
+
+// AsAPIError converts err into an APIError. Errors that are not APIErrors are classified
+// as timeouts if caused by an exceeded deadline, and as internal errors otherwise.
+func AsAPIError(err error) *APIError {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return NewTimeoutError("", "request timed out waiting for upstream APIs", err)
+	}
+	return &APIError{Kind: KindInternal, Message: "internal server error", Err: err}
+}
@@ -1,6 +1,9 @@
 package utils
 
-import "fmt"
+import (
+	"encoding/json"
+	"fmt"
+)
 
 // Country struct for displaying the country information
 type Country struct {
@@ -28,10 +31,32 @@ type yearValue struct {
 
 // APIStatus struct for displaying the status of the APIs
 type APIStatus struct {
-	CountriesNowAPI  string `json:"countriesnowapi"`
-	RestCountriesAPI string `json:"restcountriesapi"`
-	Version          string `json:"version"`
-	Uptime           string `json:"uptime"`
+	CountriesNowAPI  string          `json:"countriesnowapi"`
+	RestCountriesAPI string          `json:"restcountriesapi"`
+	Version          string          `json:"version"`
+	Uptime           string          `json:"uptime"`
+	Cache            *CacheStatus    `json:"cache,omitempty"`
+	Breakers         []BreakerStatus `json:"circuitbreakers,omitempty"`
+}
+
+// BreakerStatus struct for displaying the state of the circuit breaker of an upstream API
+type BreakerStatus struct {
+	API            string `json:"api"`
+	State          string `json:"state"`
+	Failures       int    `json:"failures"`
+	LastTransition string `json:"lasttransition"`
+}
+
+// CacheStatus struct for displaying the counters of the upstream response cache
+type CacheStatus struct {
+	Hits                 int64  `json:"hits"`
+	Misses               int64  `json:"misses"`
+	Stale                int64  `json:"stale"`
+	Entries              int    `json:"entries"`
+	MaxEntries           int    `json:"maxentries"`
+	TTL                  string `json:"ttl"`
+	StaleWhileRevalidate string `json:"stalewhilerevalidate"`
+	Persistent           bool   `json:"persistent"`
 }
 
 // NewAPIStatus creates a new APIStatus instance with the provided API statuses and uptime.
@@ -61,6 +86,79 @@ type CountryInfo struct {
 	Flag       string            `json:"flag"`
 	Capital    string            `json:"capital"`
 	Cities     []string          `json:"cities"`
+
+	// Optional fields, only returned when selected with the include parameter
+	OfficialName string                `json:"officialname,omitempty"`
+	NativeNames  map[string]NativeName `json:"nativenames,omitempty"` // Keyed by language code
+	Currencies   map[string]Currency   `json:"currencies,omitempty"`  // Keyed by ISO 4217 currency code
+	Area         *float64              `json:"area,omitempty"`        // In square kilometres
+	LatLng       []float64             `json:"latlng,omitempty"`
+	Timezones    []string              `json:"timezones,omitempty"`
+	CallingCodes []string              `json:"callingcodes,omitempty"` // E.g. "+47"
+	TLDs         []string              `json:"tlds,omitempty"`
+	Subregion    *string               `json:"subregion,omitempty"`
+	UNMember     *bool                 `json:"unmember,omitempty"`
+}
+
+// CountryInfoV2 struct for displaying the country information in version 2 of the API, listing every capital
+// of the country (e.g. Pretoria, Bloemfontein and Cape Town for South Africa) instead of only the first one.
+// Countries without a capital (e.g. Antarctica) have a null capital.
+//
+// The capital field shadows the one of the embedded CountryInfo, so all other fields are shared with version 1.
+type CountryInfoV2 struct {
+	CountryInfo
+	Capital []string `json:"capital"`
+}
+
+// NativeName struct for displaying the name of a country in one of its official languages
+type NativeName struct {
+	Common   string `json:"common"`
+	Official string `json:"official"`
+}
+
+// Currency struct for displaying a currency used in a country
+type Currency struct {
+	Name   string `json:"name"`
+	Symbol string `json:"symbol"`
+}
+
+// CityPage struct for displaying a page of the cities of a country
+type CityPage struct {
+	Country string   `json:"country"` // The ISO 3166-1 alpha-2 code of the country
+	Total   int      `json:"total"`   // The number of cities, after filtering by prefix
+	Offset  int      `json:"offset"`
+	Limit   int      `json:"limit"`
+	Cities  []string `json:"cities"`
+	Next    string   `json:"next,omitempty"` // The link to the next page, if there is one
+}
+
+// State struct for displaying a state (or other first-level subdivision) of a country
+type State struct {
+	Name string `json:"name"`
+	Code string `json:"code"`
+}
+
+// StateList struct for displaying the states of a country
+type StateList struct {
+	Country string  `json:"country"` // The ISO 3166-1 alpha-2 code of the country
+	States  []State `json:"states"`
+}
+
+// StateCities struct for displaying the cities of a state
+type StateCities struct {
+	Country string   `json:"country"` // The ISO 3166-1 alpha-2 code of the country
+	State   State    `json:"state"`
+	Cities  []string `json:"cities"`
+}
+
+// SearchResult struct for displaying a country matching a search query
+type SearchResult struct {
+	Name      string `json:"name"`      // The common name of the country
+	Alpha2    string `json:"alpha2"`    // The ISO 3166-1 alpha-2 code
+	Alpha3    string `json:"alpha3"`    // The ISO 3166-1 alpha-3 code
+	Flag      string `json:"flag"`      // The flag emoji
+	Matched   string `json:"matched"`   // The name (or code) of the country that matched the query
+	MatchedOn string `json:"matchedon"` // Which name matched: code, common, official, native or alt
 }
 
 // ErrorResponse represents the JSON error message structure
@@ -70,19 +168,152 @@ type ErrorResponse struct {
 }
 
 type PopulationInfo struct {
+	Mean   int                      `json:"mean"`
+	Values []YearValue              `json:"values"`
+	Stats  *SelectedPopulationStats `json:"stats,omitempty"` // Only set if statistics were requested
+}
+
+// PopulationStats struct for holding statistics of the population history of a country.
+// Statistics that are undefined for the values (e.g. the growth of a single year) are null.
+type PopulationStats struct {
+	Median       *float64     `json:"median"`
+	Min          *YearValue   `json:"min"`          // The smallest population, and its (earliest) year
+	Max          *YearValue   `json:"max"`          // The largest population, and its (earliest) year
+	StdDev       *float64     `json:"stddev"`       // The population standard deviation
+	Changes      []YearChange `json:"changes"`      // The change from each year to the next year with data
+	CAGR         *float64     `json:"cagr"`         // The compound annual growth rate in percent, over the selected years
+	DoublingTime *float64     `json:"doublingtime"` // The number of years to double the population at the CAGR, if it grows
+}
+
+// SelectedPopulationStats struct for displaying the requested statistics of the population history of a country,
+// each encoded as in PopulationStats. Statistics that were not requested are left out,
+// and requested statistics that are undefined for the values are null.
+type SelectedPopulationStats struct {
+	Median       json.RawMessage `json:"median,omitempty"`
+	Min          json.RawMessage `json:"min,omitempty"`
+	Max          json.RawMessage `json:"max,omitempty"`
+	StdDev       json.RawMessage `json:"stddev,omitempty"`
+	Changes      json.RawMessage `json:"changes,omitempty"`
+	CAGR         json.RawMessage `json:"cagr,omitempty"`
+	DoublingTime json.RawMessage `json:"doublingtime,omitempty"`
+}
+
+// YearChange struct for displaying the change of the population since the previous year with data
+type YearChange struct {
+	Year     int      `json:"year"`
+	Absolute int      `json:"absolute"`
+	Percent  *float64 `json:"percent"` // Null if the previous population is zero
+}
+
+type YearValue struct {
+	Year      int  `json:"year"`
+	Value     int  `json:"value"`
+	Estimated bool `json:"estimated,omitempty"` // Set when the value is filled in for a year missing from the data
+}
+
+// PopulationComparison struct for displaying the population of several countries side by side
+type PopulationComparison struct {
+	Countries []ComparedPopulation `json:"countries"` // The population history of every country, in the requested order
+	Years     []YearComparison     `json:"years"`     // The populations aligned by year, for every year with data for any country
+}
+
+// ComparedPopulation struct for displaying the population history of one of the compared countries
+type ComparedPopulation struct {
+	Code   string      `json:"code"` // The ISO 3166-1 alpha-2 code of the country
+	Name   string      `json:"name"` // The common name of the country
 	Mean   int         `json:"mean"`
 	Values []YearValue `json:"values"`
 }
 
-type YearValue struct {
-	Year  int `json:"year"`
-	Value int `json:"value"`
+// YearComparison struct for displaying the populations of the compared countries in one year
+type YearComparison struct {
+	Year      int                `json:"year"`
+	Total     int                `json:"total"`             // The combined population of the countries with data for the year
+	Countries []CountryYearValue `json:"countries"`         // The countries with data for the year, in the requested order
+	Missing   []string           `json:"missing,omitempty"` // The codes of the countries without data for the year
+}
+
+// CountryYearValue struct for displaying the population of one of the compared countries in a year, relative to the others
+type CountryYearValue struct {
+	Code       string   `json:"code"`
+	Value      int      `json:"value"`
+	Estimated  bool     `json:"estimated,omitempty"` // Set when the value is filled in for a year missing from the data
+	Share      float64  `json:"share"`               // The share of the combined population, in percent
+	Growth     *float64 `json:"growth"`              // The annual growth since the previous year with data in percent, null for the first year
+	GrowthRank *int     `json:"growthrank"`          // The rank of the growth among the countries, 1 for the fastest, null without growth
+}
+
+// PopulationProjection struct for displaying the projected population of a country
+type PopulationProjection struct {
+	Method     string               `json:"method"`     // The growth model: linear, exponential or logistic
+	Confidence float64              `json:"confidence"` // The confidence level of the lower and upper bounds, e.g. 0.95
+	Fit        ProjectionFit        `json:"fit"`
+	Values     []ProjectedYearValue `json:"values"`
+}
+
+// ProjectionFit struct for displaying how well the growth model of a projection fits the population history
+type ProjectionFit struct {
+	Points    int     `json:"points"`             // The number of years of data fitted
+	FirstYear int     `json:"firstyear"`          // The first year of data fitted
+	LastYear  int     `json:"lastyear"`           // The last year of data fitted
+	RSquared  float64 `json:"rsquared"`           // The coefficient of determination, 1 for a perfect fit
+	RMSE      float64 `json:"rmse"`               // The root-mean-square error, in people
+	Capacity  int     `json:"capacity,omitempty"` // The carrying capacity of a logistic model
+}
+
+// ProjectedYearValue struct for displaying the projected population of a year, within the bounds of its confidence band
+type ProjectedYearValue struct {
+	YearValue
+	Lower int `json:"lower"`
+	Upper int `json:"upper"`
 }
 
 type APIResponse struct {
-	Error   bool        `json:"error"`
-	Message string      `json:"message"`
-	Data    interface{} `json:"data"` // Allows any type of data
+	Error    bool        `json:"error"`
+	Message  string      `json:"message"`
+	Code     string      `json:"code,omitempty"`     // Machine-readable error code, see ErrorKind.Code
+	Data     interface{} `json:"data"`               // Allows any type of data
+	Stale    bool        `json:"stale,omitempty"`    // Set when data is served from an expired cache entry
+	Partial  bool        `json:"partial,omitempty"`  // Set when parts of the data could not be retrieved
+	Warnings []Warning   `json:"warnings,omitempty"` // Describes which parts are missing from a partial response
+
+	Suggestions []Suggestion `json:"suggestions,omitempty"` // Countries the client may have meant by an invalid country code
+}
+
+// Suggestion identifies a country the client may have meant by an invalid country code
+type Suggestion struct {
+	Code string `json:"code"` // The ISO 3166-1 alpha-2 code of the country
+	Name string `json:"name"` // The common name of the country
+}
+
+// Warning describes a part of the data that could not be retrieved from an upstream API
+type Warning struct {
+	Source  string `json:"source"`  // The upstream API that failed
+	Field   string `json:"field"`   // The field of the data left empty
+	Message string `json:"message"` // Human-readable description of the failure
+}
+
+// ProblemDetails represents an RFC 7807 problem details error document, sent instead of an
+// APIResponse to clients accepting application/problem+json
+type ProblemDetails struct {
+	Type     string `json:"type"`               // URI reference identifying the kind of problem
+	Title    string `json:"title"`              // Short summary of the kind of problem
+	Status   int    `json:"status"`             // The HTTP status code of the response
+	Detail   string `json:"detail"`             // Human-readable explanation of this occurrence
+	Instance string `json:"instance"`           // The request URI of this occurrence
+	Code     string `json:"code"`               // Machine-readable error code, see ErrorKind.Code
+	Upstream string `json:"upstream,omitempty"` // The upstream API involved, if any
+
+	Suggestions []Suggestion `json:"suggestions,omitempty"` // Countries the client may have meant by an invalid country code
+}
+
+// ProblemType documents a problem type URI, served at the URI itself
+type ProblemType struct {
+	Type        string `json:"type"`        // The problem type URI
+	Title       string `json:"title"`       // Short summary of the kind of problem
+	Status      int    `json:"status"`      // The HTTP status code of responses reporting the problem
+	Code        string `json:"code"`        // Machine-readable error code, see ErrorKind.Code
+	Description string `json:"description"` // Human-readable explanation of the kind of problem
 }
 
 type APIResponseString struct {